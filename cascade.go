@@ -0,0 +1,93 @@
+package gotenv
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ModeVar is the environment variable holding the mode of LoadCascade when none is given.
+const ModeVar = "APP_ENV"
+
+// DefaultCascade is the list of files loaded by LoadCascade, from the highest precedence to the lowest one.
+var DefaultCascade = []string{".env.{mode}.local", ".env.local", ".env.{mode}", ".env"}
+
+// cascade is the configuration of LoadCascade.
+type cascade struct {
+	dir       string
+	files     []string
+	testSkips []string
+}
+
+// CascadeOption configures LoadCascade.
+type CascadeOption func(*cascade)
+
+// WithCascadeDir sets the directory holding the files, which is the current one by default.
+func WithCascadeDir(dir string) CascadeOption {
+	return func(c *cascade) {
+		c.dir = dir
+	}
+}
+
+// WithCascadeFiles sets the files to load, from the highest precedence to the lowest one, instead of DefaultCascade.
+// In their names, `{mode}` is replaced by the mode, the names holding it being skipped when there is no mode.
+func WithCascadeFiles(files ...string) CascadeOption {
+	return func(c *cascade) {
+		c.files = files
+	}
+}
+
+// WithCascadeTestSkips sets the files skipped in the `test` mode, so that tests don't depend on the settings of a machine.
+// It defaults to `.env.local`.
+func WithCascadeTestSkips(files ...string) CascadeOption {
+	return func(c *cascade) {
+		c.testSkips = files
+	}
+}
+
+// LoadCascade loads the env files of the given mode, like `development` or `production`, following the usual convention:
+// `.env.<mode>.local`, `.env.local`, `.env.<mode>` and `.env`, the first ones taking precedence over the last ones.
+// The mode defaults to the value of the APP_ENV environment variable. In the `test` mode, `.env.local` is skipped.
+// Missing files are skipped as well, and the names of the files actually loaded are returned in the order they were loaded.
+func LoadCascade(mode string, opts ...CascadeOption) ([]string, error) {
+	return NewParser().LoadCascade(mode, opts...)
+}
+
+// OverLoadCascade is like LoadCascade but it overrides the existing environment variables.
+func OverLoadCascade(mode string, opts ...CascadeOption) ([]string, error) {
+	return NewParser(WithOverride(true)).LoadCascade(mode, opts...)
+}
+
+// LoadCascade is like the LoadCascade function, the files being loaded with the configuration of the Parser.
+// With the WithOverride option, the files are loaded from the lowest precedence to the highest one,
+// so that the variables of the first files override the ones of the last files.
+func (p *Parser) LoadCascade(mode string, opts ...CascadeOption) ([]string, error) {
+	c := &cascade{files: DefaultCascade, testSkips: []string{".env.local"}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if mode == "" {
+		mode = os.Getenv(ModeVar)
+	}
+
+	var names []string
+	for _, file := range c.files {
+		if mode == "test" && slices.Contains(c.testSkips, file) {
+			continue
+		}
+		if strings.Contains(file, "{mode}") {
+			if mode == "" {
+				continue
+			}
+			file = strings.ReplaceAll(file, "{mode}", mode)
+		}
+		names = append(names, filepath.Join(c.dir, file))
+	}
+	if p.override {
+		slices.Reverse(names)
+	}
+
+	loaded, _, err := p.load(nil, names, true)
+	return loaded, err
+}
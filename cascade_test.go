@@ -0,0 +1,163 @@
+package gotenv_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+// writeTree returns a directory holding the given files, named with slashes, a trailing slash making a directory.
+func writeTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if strings.HasSuffix(name, "/") {
+			err = os.Mkdir(path, 0o755)
+		} else {
+			err = os.WriteFile(path, []byte(content), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+var cascadeFiles = map[string]string{
+	".env":                   "A=env\nB=env\nC=env\nD=env\nE=env",
+	".env.local":             "A=local\nB=local\nC=local",
+	".env.development":       "A=development\nB=development\nD=development",
+	".env.development.local": "A=development.local",
+	".env.test":              "A=test\nD=test",
+}
+
+func TestLoadCascade(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, cascadeFiles)
+
+	os.Setenv("E", "preset")
+	loaded, err := gotenv.LoadCascade("development", gotenv.WithCascadeDir(dir))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, ".env.development.local"),
+		filepath.Join(dir, ".env.local"),
+		filepath.Join(dir, ".env.development"),
+		filepath.Join(dir, ".env"),
+	}, loaded)
+
+	assert.Equal(t, "development.local", os.Getenv("A"))
+	assert.Equal(t, "local", os.Getenv("B"))
+	assert.Equal(t, "local", os.Getenv("C"))
+	assert.Equal(t, "development", os.Getenv("D"))
+	assert.Equal(t, "preset", os.Getenv("E"))
+}
+
+func TestLoadCascade_test(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, cascadeFiles)
+
+	// .env.local is skipped, and .env.test.local is missing
+	loaded, err := gotenv.LoadCascade("test", gotenv.WithCascadeDir(dir))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, ".env.test"), filepath.Join(dir, ".env")}, loaded)
+	assert.Equal(t, "test", os.Getenv("A"))
+	assert.Equal(t, "env", os.Getenv("B"))
+	assert.Equal(t, "test", os.Getenv("D"))
+	os.Clearenv()
+
+	loaded, err = gotenv.LoadCascade("test", gotenv.WithCascadeDir(dir), gotenv.WithCascadeTestSkips())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, ".env.local"), filepath.Join(dir, ".env.test"), filepath.Join(dir, ".env")}, loaded)
+	assert.Equal(t, "local", os.Getenv("B"))
+}
+
+func TestLoadCascade_mode(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, cascadeFiles)
+
+	os.Setenv("APP_ENV", "development")
+	loaded, err := gotenv.LoadCascade("", gotenv.WithCascadeDir(dir))
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 4)
+	assert.Equal(t, "development.local", os.Getenv("A"))
+	os.Clearenv()
+
+	// without mode
+	loaded, err = gotenv.LoadCascade("", gotenv.WithCascadeDir(dir))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, ".env.local"), filepath.Join(dir, ".env")}, loaded)
+	assert.Equal(t, "local", os.Getenv("A"))
+}
+
+func TestLoadCascade_files(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, map[string]string{
+		"app.env":            "A=app\nB=app",
+		"app.production.env": "A=production",
+	})
+
+	loaded, err := gotenv.LoadCascade("production", gotenv.WithCascadeDir(dir), gotenv.WithCascadeFiles("app.{mode}.env", "app.env"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "app.production.env"), filepath.Join(dir, "app.env")}, loaded)
+	assert.Equal(t, "production", os.Getenv("A"))
+	assert.Equal(t, "app", os.Getenv("B"))
+}
+
+func TestLoadCascade_errors(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, map[string]string{
+		".env":       "A=env\nlol$wut",
+		".env.local": "B=local\nlol$wut",
+	})
+
+	loaded, err := gotenv.LoadCascade("development", gotenv.WithCascadeDir(dir))
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.EqualError(t, err, filepath.Join(dir, ".env.local")+":2:4: line `lol$wut` doesn't match format")
+	assert.Empty(t, loaded)
+	assert.Equal(t, "", os.Getenv("A"))
+	assert.Equal(t, "", os.Getenv("B"))
+
+	// invalid lines skipped
+	p := gotenv.NewParser(gotenv.WithStrict(false))
+	loaded, err = p.LoadCascade("development", gotenv.WithCascadeDir(dir))
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.EqualError(t, err, filepath.Join(dir, ".env.local")+":2:4: line `lol$wut` doesn't match format\n"+
+		filepath.Join(dir, ".env")+":2:4: line `lol$wut` doesn't match format")
+	assert.Equal(t, []string{filepath.Join(dir, ".env.local"), filepath.Join(dir, ".env")}, loaded)
+	assert.Equal(t, "env", os.Getenv("A"))
+	assert.Equal(t, "local", os.Getenv("B"))
+
+	// a directory can't be read as a file
+	err = os.Mkdir(filepath.Join(dir, ".env.development"), 0o755)
+	assert.Nil(t, err)
+	_, err = gotenv.LoadCascade("development", gotenv.WithCascadeDir(dir), gotenv.WithCascadeFiles(".env.{mode}"))
+	assert.Error(t, err)
+}
+
+func TestOverLoadCascade(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, cascadeFiles)
+
+	os.Setenv("E", "preset")
+	loaded, err := gotenv.OverLoadCascade("development", gotenv.WithCascadeDir(dir))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, ".env"),
+		filepath.Join(dir, ".env.development"),
+		filepath.Join(dir, ".env.local"),
+		filepath.Join(dir, ".env.development.local"),
+	}, loaded)
+
+	assert.Equal(t, "development.local", os.Getenv("A"))
+	assert.Equal(t, "local", os.Getenv("B"))
+	assert.Equal(t, "development", os.Getenv("D"))
+	assert.Equal(t, "env", os.Getenv("E"))
+}
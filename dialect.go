@@ -0,0 +1,164 @@
+package gotenv
+
+import "strconv"
+
+// Dialect is a flavor of the env file format, following the grammar of another implementation.
+// It's selected with the WithDialect option.
+type Dialect int
+
+const (
+	// DialectGotenv is the format of gotenv, which is the default. It accepts the `export` keyword, `=` and `:` separators,
+	// single, double and backtick quotes, heredocs, line continuations and comments anywhere after unquoted values.
+	// Escape sequences are decoded in double quoted values and variables are expanded, shell parameter expansions included.
+	DialectGotenv Dialect = iota
+
+	// DialectDockerCompose follows the env files of Docker Compose: the separator is `=` alone, a comment following
+	// an unquoted value has to be preceded by a blank, and the parameter expansions assigning a default value,
+	// like ${VAR:=default}, are not supported.
+	DialectDockerCompose
+
+	// DialectNodeDotenv follows the dotenv package of Node.js: keys may contain dashes, values may be quoted with
+	// backticks, only \n and \r are decoded in double quoted values and variables are not expanded.
+	DialectNodeDotenv
+
+	// DialectPythonDotenv follows the python-dotenv package: the separator is `=` alone, keys may contain dashes,
+	// a comment following an unquoted value has to be preceded by a blank, \\ and \' are decoded in single quoted values,
+	// and only the ${VAR} and ${VAR:-default} forms of variables are expanded.
+	DialectPythonDotenv
+
+	// DialectRubyDotenv follows the dotenv gem of Ruby: double quoted values are unescaped like WithLegacyEscapes,
+	// and the parameter expansions like ${VAR:-default} are not supported.
+	DialectRubyDotenv
+
+	// DialectPOSIXShell follows the assignments of a POSIX shell script: the separator is `=` alone, without blanks around it,
+	// and keys are names made of letters, digits and underscores. A value is a word made of quoted and unquoted parts
+	// concatenated, like 'a'"b"c, ending at the first unescaped blank outside of quotes and parameter expansions.
+	// Backslashes escape any character in its unquoted parts, while its double quoted parts only decode \$, \`, \", \\
+	// and line breaks, and its single quoted parts are read literally. Unlike a shell, the quotes within the word
+	// of a parameter expansion, like ${VAR:-'a b'}, are kept.
+	DialectPOSIXShell
+)
+
+var dialectNames = [...]string{
+	DialectGotenv:        "gotenv",
+	DialectDockerCompose: "docker-compose",
+	DialectNodeDotenv:    "node-dotenv",
+	DialectPythonDotenv:  "python-dotenv",
+	DialectRubyDotenv:    "ruby-dotenv",
+	DialectPOSIXShell:    "posix-shell",
+}
+
+func (d Dialect) String() string {
+	if d < 0 || int(d) >= len(dialectNames) {
+		return "Dialect(" + strconv.Itoa(int(d)) + ")"
+	}
+	return dialectNames[d]
+}
+
+// syntax is the grammar of the env files read by a Parser.
+type syntax struct {
+	separators      string
+	export          bool
+	keyPunct        string // characters accepted in keys besides letters, digits and underscores
+	spacedSeparator bool   // blanks are accepted around the separator
+	quotes          string
+	heredocs        bool
+	continuation    bool // an unquoted value ending with a backslash is continued on the next line
+	unquotedBlanks  bool // unquoted values may hold blanks
+	spacedComment   bool // a comment following an unquoted value has to be preceded by a blank
+	shellEscapes    bool // backslashes escape any character in unquoted values
+	words           bool // values are made of quoted and unquoted parts concatenated, like the words of a shell
+	singleEscapes   bool // \\ and \' are decoded in single quoted values
+	escapes         escaping
+	braced          bool     // only the ${VAR} form of variables is expanded
+	uppercaseNames  bool     // only the names made of uppercase letters, digits and underscores are expanded
+	operators       []string // operators of the supported parameter expansions
+}
+
+// Operators of the shell parameter expansions
+var allOperators = []string{":-", "-", ":=", "=", ":?", "?", ":+", "+"}
+
+// dialects holds the grammar of each dialect, along with whether it expands variables.
+var dialects = [...]struct {
+	syntax
+	expansion bool
+}{
+	DialectGotenv: {syntax{
+		separators:      "=:",
+		export:          true,
+		keyPunct:        ".",
+		spacedSeparator: true,
+		quotes:          "'\"`",
+		heredocs:        true,
+		continuation:    true,
+		unquotedBlanks:  true,
+		escapes:         escapesFull,
+		operators:       allOperators,
+	}, true},
+	DialectDockerCompose: {syntax{
+		separators:      "=",
+		export:          true,
+		keyPunct:        ".",
+		spacedSeparator: true,
+		quotes:          "'\"",
+		unquotedBlanks:  true,
+		spacedComment:   true,
+		escapes:         escapesCompose,
+		operators:       []string{":-", "-", ":?", "?", ":+", "+"},
+	}, true},
+	DialectNodeDotenv: {syntax{
+		separators:      "=:",
+		export:          true,
+		keyPunct:        ".-",
+		spacedSeparator: true,
+		quotes:          "'\"`",
+		unquotedBlanks:  true,
+		escapes:         escapesNewlines,
+	}, false},
+	DialectPythonDotenv: {syntax{
+		separators:      "=",
+		export:          true,
+		keyPunct:        ".-",
+		spacedSeparator: true,
+		quotes:          "'\"",
+		unquotedBlanks:  true,
+		spacedComment:   true,
+		singleEscapes:   true,
+		escapes:         escapesPython,
+		braced:          true,
+		operators:       []string{":-"},
+	}, true},
+	DialectRubyDotenv: {syntax{
+		separators:      "=:",
+		export:          true,
+		keyPunct:        ".",
+		spacedSeparator: true,
+		quotes:          "'\"",
+		unquotedBlanks:  true,
+		escapes:         escapesLegacy,
+	}, true},
+	DialectPOSIXShell: {syntax{
+		separators:    "=",
+		export:        true,
+		quotes:        "'\"",
+		continuation:  true,
+		spacedComment: true,
+		shellEscapes:  true,
+		words:         true,
+		escapes:       escapesShell,
+		operators:     allOperators,
+	}, true},
+}
+
+// WithDialect sets the grammar of the env files to the one of another implementation, DialectGotenv being the default.
+// It sets the separators, the export keyword and the expansion of variables as well,
+// which can be changed by the options following it. An unknown dialect is ignored.
+func WithDialect(d Dialect) Option {
+	return func(p *Parser) {
+		if d < 0 || int(d) >= len(dialects) {
+			return
+		}
+		p.syntax = dialects[d].syntax
+		p.expansion = dialects[d].expansion
+	}
+}
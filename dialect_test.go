@@ -0,0 +1,272 @@
+package gotenv_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+var dialects = []gotenv.Dialect{
+	gotenv.DialectGotenv,
+	gotenv.DialectDockerCompose,
+	gotenv.DialectNodeDotenv,
+	gotenv.DialectPythonDotenv,
+	gotenv.DialectRubyDotenv,
+	gotenv.DialectPOSIXShell,
+}
+
+// everywhere returns env as the result of every dialect, unless it's overridden.
+// A nil result means the input is rejected, as an invalid line or a missing quote.
+func everywhere(env gotenv.Env, overrides map[gotenv.Dialect]gotenv.Env) map[gotenv.Dialect]gotenv.Env {
+	results := make(map[gotenv.Dialect]gotenv.Env)
+	for _, d := range dialects {
+		results[d] = env
+		if o, ok := overrides[d]; ok {
+			results[d] = o
+		}
+	}
+	return results
+}
+
+// dialectCorpus holds the inputs read differently by the dialects, along with the results of each of them.
+var dialectCorpus = []struct {
+	name    string
+	in      string
+	results map[gotenv.Dialect]gotenv.Env
+}{
+	{
+		"plain",
+		"A=1",
+		everywhere(gotenv.Env{"A": "1"}, nil),
+	},
+	{
+		"export keyword",
+		"export A=1",
+		everywhere(gotenv.Env{"A": "1"}, nil),
+	},
+	{
+		"yaml separator",
+		"A: 1",
+		everywhere(gotenv.Env{"A": "1"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectDockerCompose: nil,
+			gotenv.DialectPythonDotenv:  nil,
+			gotenv.DialectPOSIXShell:    nil,
+		}),
+	},
+	{
+		"blanks around the separator",
+		"A = 1",
+		everywhere(gotenv.Env{"A": "1"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectPOSIXShell: nil,
+		}),
+	},
+	{
+		"inline comment",
+		"A=1 # comment",
+		everywhere(gotenv.Env{"A": "1"}, nil),
+	},
+	{
+		"hash within an unquoted value",
+		"A=1#2",
+		everywhere(gotenv.Env{"A": "1"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectDockerCompose: {"A": "1#2"},
+			gotenv.DialectPythonDotenv:  {"A": "1#2"},
+			gotenv.DialectPOSIXShell:    {"A": "1#2"},
+		}),
+	},
+	{
+		"single quotes",
+		"B=b\nA='$B\\n'",
+		everywhere(gotenv.Env{"A": `$B\n`, "B": "b"}, nil),
+	},
+	{
+		"escaped single quote",
+		`A='it\'s'`,
+		everywhere(gotenv.Env{"A": `it\'s`}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectPythonDotenv: {"A": "it's"},
+			gotenv.DialectPOSIXShell:   nil,
+		}),
+	},
+	{
+		"concatenated quotes",
+		`A='a'"b"`,
+		everywhere(gotenv.Env{"A": `'a'"b"`}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectPOSIXShell: {"A": "ab"},
+		}),
+	},
+	{
+		"quoted blanks within an unquoted value",
+		`A=a'b c'`,
+		everywhere(gotenv.Env{"A": "a'b c'"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectPOSIXShell: {"A": "ab c"},
+		}),
+	},
+	{
+		"concatenated words",
+		"B=b\nA=\"$B\"'$B'$B\\ x",
+		everywhere(gotenv.Env{"A": `"b"'b'b\ x`, "B": "b"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectNodeDotenv:   {"A": `"$B"'$B'$B\ x`, "B": "b"},
+			gotenv.DialectPythonDotenv: {"A": `"$B"'$B'$B\ x`, "B": "b"},
+			gotenv.DialectPOSIXShell:   {"A": "b$Bb x", "B": "b"},
+		}),
+	},
+	{
+		"escape sequences in double quotes",
+		`A="a\tb\\c\nd"`,
+		everywhere(gotenv.Env{"A": "a\tb\\c\nd"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectNodeDotenv: {"A": "a\\tb\\\\c\nd"},
+			gotenv.DialectRubyDotenv: {"A": "atb\\c\nd"},
+			gotenv.DialectPOSIXShell: {"A": `a\tb\c\nd`},
+		}),
+	},
+	{
+		"variable expansion",
+		"B=b\nA=$B${B}",
+		everywhere(gotenv.Env{"A": "bb", "B": "b"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectNodeDotenv:   {"A": "$B${B}", "B": "b"},
+			gotenv.DialectPythonDotenv: {"A": "$Bb", "B": "b"},
+		}),
+	},
+	{
+		"default value",
+		"A=${B:-x}",
+		everywhere(gotenv.Env{"A": "x"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectNodeDotenv: {"A": "${B:-x}"},
+			gotenv.DialectRubyDotenv: {"A": "${B:-x}"},
+		}),
+	},
+	{
+		"blanks within a default value",
+		"A=${B:-a b}",
+		everywhere(gotenv.Env{"A": "a b"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectNodeDotenv: {"A": "${B:-a b}"},
+			gotenv.DialectRubyDotenv: {"A": "${B:-a b}"},
+		}),
+	},
+	{
+		"assigned default value",
+		"A=${B:=x}",
+		everywhere(gotenv.Env{"A": "${B:=x}"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectGotenv:     {"A": "x", "B": "x"},
+			gotenv.DialectPOSIXShell: {"A": "x", "B": "x"},
+		}),
+	},
+	{
+		"backticks",
+		"A=`it's`",
+		everywhere(gotenv.Env{"A": "`it's`"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectGotenv:     {"A": "it's"},
+			gotenv.DialectNodeDotenv: {"A": "it's"},
+			gotenv.DialectPOSIXShell: nil,
+		}),
+	},
+	{
+		"multi-line double quotes",
+		"A=\"a\nb\"",
+		everywhere(gotenv.Env{"A": "a\nb"}, nil),
+	},
+	{
+		"heredoc",
+		"A=<<EOF\na\nEOF",
+		everywhere(nil, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectGotenv: {"A": "a"},
+		}),
+	},
+	{
+		"line continuation",
+		"A=a\\\nb",
+		everywhere(nil, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectGotenv:     {"A": "ab"},
+			gotenv.DialectPOSIXShell: {"A": "ab"},
+		}),
+	},
+	{
+		"blanks within an unquoted value",
+		"A=a b",
+		everywhere(gotenv.Env{"A": "a b"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectPOSIXShell: nil,
+		}),
+	},
+	{
+		"backslashes in unquoted values",
+		`A=a\ b\#c`,
+		everywhere(gotenv.Env{"A": `a\ b`}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectDockerCompose: {"A": `a\ b\#c`},
+			gotenv.DialectNodeDotenv:    {"A": `a\ b\`},
+			gotenv.DialectPythonDotenv:  {"A": `a\ b\#c`},
+			gotenv.DialectRubyDotenv:    {"A": `a\ b\`},
+			gotenv.DialectPOSIXShell:    {"A": "a b#c"},
+		}),
+	},
+	{
+		"dashed key",
+		"A-B=1",
+		everywhere(nil, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectNodeDotenv:   {"A-B": "1"},
+			gotenv.DialectPythonDotenv: {"A-B": "1"},
+		}),
+	},
+	{
+		"dotted key",
+		"a.b=1",
+		everywhere(gotenv.Env{"a.b": "1"}, map[gotenv.Dialect]gotenv.Env{
+			gotenv.DialectPOSIXShell: nil,
+		}),
+	},
+}
+
+func TestWithDialect(t *testing.T) {
+	for _, tc := range dialectCorpus {
+		for _, d := range dialects {
+			p := gotenv.NewParser(gotenv.WithDialect(d), gotenv.WithLookup(nil))
+			env, err := p.Parse(strings.NewReader(tc.in))
+
+			want := tc.results[d]
+			if want == nil {
+				rejected := errors.Is(err, gotenv.ErrInvalidLine) || errors.Is(err, gotenv.ErrMissingQuote)
+				assert.True(t, rejected, "%s: %s: %v", d, tc.name, err)
+				continue
+			}
+			if assert.Nil(t, err, "%s: %s", d, tc.name) {
+				assert.Equal(t, want, env, "%s: %s", d, tc.name)
+			}
+		}
+	}
+}
+
+func TestWithDialect_options(t *testing.T) {
+	// the options following the dialect override it
+	p := gotenv.NewParser(gotenv.WithDialect(gotenv.DialectNodeDotenv), gotenv.WithExpansion(true), gotenv.WithLookup(nil))
+	env, err := p.Parse(strings.NewReader("B=b\nA=$B"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": "b", "B": "b"}, env)
+
+	p = gotenv.NewParser(gotenv.WithDialect(gotenv.Dialect(42)))
+	env, err = p.Parse(strings.NewReader("A=<<EOF\na\nEOF"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": "a"}, env)
+}
+
+func TestWithDialect_posixShellWords(t *testing.T) {
+	p := gotenv.NewParser(gotenv.WithDialect(gotenv.DialectPOSIXShell), gotenv.WithLookup(nil))
+	env, err := p.Parse(strings.NewReader("A=a\"b\nc\"'d\ne'\\\nf # comment\nB=\"${C:-$A}\"z"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": "ab\ncd\nef", "B": "ab\ncd\nefz"}, env)
+
+	_, err = p.Parse(strings.NewReader("A='a'b 'c'"))
+	assert.EqualError(t, err, "1:8: line `A='a'b 'c'` doesn't match format")
+
+	p = gotenv.NewParser(gotenv.WithDialect(gotenv.DialectPOSIXShell), gotenv.WithExpansion(false))
+	env, err = p.Parse(strings.NewReader(`A="$B"'$C'\$D\ x`))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": `$B$C\$D x`}, env)
+}
+
+func TestDialect_String(t *testing.T) {
+	assert.Equal(t, "gotenv", gotenv.DialectGotenv.String())
+	assert.Equal(t, "posix-shell", gotenv.DialectPOSIXShell.String())
+	assert.Equal(t, "Dialect(42)", gotenv.Dialect(42).String())
+}
@@ -0,0 +1,63 @@
+package gotenv
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultDirPattern is the pattern of the files loaded by LoadDir when none is given.
+const DefaultDirPattern = "*.env"
+
+// LoadDir loads the files of the directory dir whose names match one of the patterns, `*.env` by default,
+// in lexical order, like `10-base.env`, `20-db.env` and `90-overrides.env`. The patterns follow the syntax of filepath.Match.
+// Like Load, it doesn't override the existing environment variables, and the first file setting a variable wins.
+// It returns the names of the files loaded, the errors of invalid lines holding the name of their file.
+func LoadDir(dir string, patterns ...string) ([]string, error) {
+	return NewParser().LoadDir(dir, patterns...)
+}
+
+// OverLoadDir is like LoadDir but it overrides the existing environment variables,
+// so that the variables of the last files override the ones of the first files.
+func OverLoadDir(dir string, patterns ...string) ([]string, error) {
+	return NewParser(WithOverride(true)).LoadDir(dir, patterns...)
+}
+
+// LoadDir is like the LoadDir function, the files being loaded with the configuration of the Parser.
+func (p *Parser) LoadDir(dir string, patterns ...string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{DefaultDirPattern}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matched, err := matchAny(patterns, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			names = append(names, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	loaded, _, err := p.load(nil, names, false)
+	return loaded, err
+}
+
+// matchAny reports whether name matches one of the patterns.
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := filepath.Match(pattern, name)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,100 @@
+package gotenv_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+var dirFiles = map[string]string{
+	"90-overrides.env": "A=overrides\nD=overrides",
+	"10-base.env":      "A=base\nB=base\nC=base",
+	"20-db.env":        "B=db\nDB_URL=postgres://${B}",
+	"README.md":        "not an env file",
+	"30-cache.conf":    "C=cache",
+}
+
+func TestLoadDir(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, dirFiles)
+	err := os.Mkdir(filepath.Join(dir, "50-nested.env"), 0o755)
+	assert.Nil(t, err)
+
+	os.Setenv("D", "preset")
+	loaded, err := gotenv.LoadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "10-base.env"),
+		filepath.Join(dir, "20-db.env"),
+		filepath.Join(dir, "90-overrides.env"),
+	}, loaded)
+
+	assert.Equal(t, "base", os.Getenv("A"))
+	assert.Equal(t, "base", os.Getenv("B"))
+	assert.Equal(t, "base", os.Getenv("C"))
+	assert.Equal(t, "preset", os.Getenv("D"))
+	assert.Equal(t, "postgres://base", os.Getenv("DB_URL"))
+}
+
+func TestLoadDir_patterns(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, dirFiles)
+
+	loaded, err := gotenv.LoadDir(dir, "*.conf", "2?-*.env")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "20-db.env"), filepath.Join(dir, "30-cache.conf")}, loaded)
+	assert.Equal(t, "db", os.Getenv("B"))
+	assert.Equal(t, "cache", os.Getenv("C"))
+	assert.Equal(t, "", os.Getenv("A"))
+
+	_, err = gotenv.LoadDir(dir, "[")
+	assert.ErrorIs(t, err, filepath.ErrBadPattern)
+}
+
+func TestLoadDir_errors(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, map[string]string{
+		"10-base.env":   "A=base",
+		"20-broken.env": "B=broken\nlol$wut",
+		"30-last.env":   "C=last\nlol$wut",
+	})
+
+	loaded, err := gotenv.LoadDir(dir)
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.EqualError(t, err, filepath.Join(dir, "20-broken.env")+":2:4: line `lol$wut` doesn't match format")
+	assert.Equal(t, []string{filepath.Join(dir, "10-base.env")}, loaded)
+	assert.Equal(t, "base", os.Getenv("A"))
+	assert.Equal(t, "", os.Getenv("B"))
+	assert.Equal(t, "", os.Getenv("C"))
+
+	// invalid lines skipped
+	p := gotenv.NewParser(gotenv.WithStrict(false))
+	loaded, err = p.LoadDir(dir)
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.EqualError(t, err, filepath.Join(dir, "20-broken.env")+":2:4: line `lol$wut` doesn't match format\n"+
+		filepath.Join(dir, "30-last.env")+":2:4: line `lol$wut` doesn't match format")
+	assert.Equal(t, []string{filepath.Join(dir, "10-base.env"), filepath.Join(dir, "20-broken.env"), filepath.Join(dir, "30-last.env")}, loaded)
+	assert.Equal(t, "broken", os.Getenv("B"))
+	assert.Equal(t, "last", os.Getenv("C"))
+
+	_, err = gotenv.LoadDir(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestOverLoadDir(t *testing.T) {
+	defer os.Clearenv()
+	dir := writeTree(t, dirFiles)
+
+	os.Setenv("D", "preset")
+	loaded, err := gotenv.OverLoadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, loaded, 3)
+
+	assert.Equal(t, "overrides", os.Getenv("A"))
+	assert.Equal(t, "db", os.Getenv("B"))
+	assert.Equal(t, "base", os.Getenv("C"))
+	assert.Equal(t, "overrides", os.Getenv("D"))
+}
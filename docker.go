@@ -0,0 +1,120 @@
+package gotenv
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseDockerEnvFile parses r as an env file of `docker run --env-file` and returns the Env key/value pair of valid variables.
+// Every value is read literally, up to the end of the line: quotes, escape sequences and variables are kept as written.
+// A line holding a key alone passes the variable through from the environment, it's skipped when the variable isn't set.
+// This function is returning the first invalid line as a ParseError, along with the variables found before it.
+func ParseDockerEnvFile(r io.Reader) (Env, error) {
+	env := make(Env)
+
+	z, _, err := decode(r)
+	if err != nil {
+		return env, err
+	}
+
+	scanner := bufio.NewScanner(z)
+	scanner.Buffer(nil, DefaultMaxValueSize)
+	n := 0
+	for scanner.Scan() {
+		n++
+		raw := scanner.Text()
+		line := strings.TrimLeftFunc(raw, unicode.IsSpace)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		indent := len(raw) - len(line)
+
+		if !utf8.ValidString(line) {
+			return env, &ParseError{Line: n, Column: indent + 1, Text: line, Err: ErrInvalidLine}
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if key == "" {
+			return env, &ParseError{Line: n, Column: indent + 1, Text: line, Err: ErrInvalidLine}
+		}
+		if i := strings.IndexAny(key, " \t"); i >= 0 {
+			return env, &ParseError{Line: n, Column: indent + i + 1, Text: line, Err: ErrInvalidLine}
+		}
+
+		if !ok {
+			// passed through from the environment
+			if val, ok = os.LookupEnv(key); !ok {
+				continue
+			}
+		}
+		env[key] = val
+	}
+
+	return env, scanner.Err()
+}
+
+// ReadDockerEnvFile is like ParseDockerEnvFile but it reads the file, the errors of invalid lines holding the filename.
+func ReadDockerEnvFile(filename string) (Env, error) {
+	return readWith(filename, ParseDockerEnvFile)
+}
+
+// readWith parses the file with parse, recording the filename on the errors of invalid lines.
+func readWith(filename string, parse func(io.Reader) (Env, error)) (Env, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env, err := parse(f)
+	return env, withFilename(err, filename)
+}
+
+// MarshalDockerEnvFile outputs the given environment as an env file of `docker run --env-file`.
+// Variables will be sorted by name and their values written literally, without quotes.
+// A variable which Docker can't read back, like a value holding a line break, is reported as an error holding ErrUnrepresentable.
+func MarshalDockerEnvFile(env Env) (string, error) {
+	lines := make([]string, 0, len(env))
+	for k, v := range env {
+		if msg := dockerProblem(k, v); msg != "" {
+			return "", &varError{name: k, msg: msg, err: ErrUnrepresentable}
+		}
+		lines = append(lines, k+"="+v)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// WriteDockerEnvFile serializes the given environment like MarshalDockerEnvFile and writes it to a file
+func WriteDockerEnvFile(env Env, filename string) error {
+	content, err := MarshalDockerEnvFile(env)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, content)
+}
+
+// dockerProblem returns the reason why the variable key can't be written in a Docker env file, or an empty string.
+func dockerProblem(key, val string) string {
+	switch {
+	case key == "":
+		return "empty name"
+	case key[0] == '#':
+		return "name starting with a hash"
+	case strings.ContainsFunc(key, unicode.IsSpace):
+		return "name holding a blank"
+	case strings.ContainsAny(key, "=\x00"):
+		return "name holding an equal sign or a NUL character"
+	case strings.ContainsAny(val, "\n\r"):
+		return "value holding a line break"
+	case strings.ContainsRune(val, 0):
+		return "value holding a NUL character"
+	case !utf8.ValidString(key) || !utf8.ValidString(val):
+		return "invalid UTF-8"
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package gotenv_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+var dockerFormats = []struct {
+	in  string
+	out gotenv.Env
+}{
+	{`FOO=bar`, gotenv.Env{"FOO": "bar"}},
+	{`FOO="bar"`, gotenv.Env{"FOO": `"bar"`}},
+	{`FOO='bar'`, gotenv.Env{"FOO": "'bar'"}},
+	{`FOO=bar # not a comment`, gotenv.Env{"FOO": "bar # not a comment"}},
+	{`FOO=$BAR\n${BAZ}`, gotenv.Env{"FOO": `$BAR\n${BAZ}`}},
+	{"FOO= bar \t", gotenv.Env{"FOO": " bar \t"}},
+	{"FOO=a=b", gotenv.Env{"FOO": "a=b"}},
+	{"FOO=", gotenv.Env{"FOO": ""}},
+	{"  \tFOO=bar", gotenv.Env{"FOO": "bar"}},
+	{"# comment\n  # indented comment\n\nFOO=bar", gotenv.Env{"FOO": "bar"}},
+	{"FOO=1\r\nBAR=2\r\n", gotenv.Env{"FOO": "1", "BAR": "2"}},
+	{"FOO=1\nFOO=2", gotenv.Env{"FOO": "2"}},
+	{"foo.bar-baz=1", gotenv.Env{"foo.bar-baz": "1"}},
+	{"export FOO=bar", nil},
+	{"\xEF\xBB\xBFFOO=bar", gotenv.Env{"FOO": "bar"}},
+}
+
+func TestParseDockerEnvFile(t *testing.T) {
+	for _, tt := range dockerFormats {
+		env, err := gotenv.ParseDockerEnvFile(strings.NewReader(tt.in))
+		if tt.out == nil {
+			assert.ErrorIs(t, err, gotenv.ErrInvalidLine, tt.in)
+			continue
+		}
+		assert.Nil(t, err, tt.in)
+		assert.Equal(t, tt.out, env, tt.in)
+	}
+}
+
+func TestParseDockerEnvFile_passThrough(t *testing.T) {
+	os.Setenv("DOCKER_HOST_VAR", "from host")
+	defer os.Clearenv()
+
+	env, err := gotenv.ParseDockerEnvFile(strings.NewReader("DOCKER_HOST_VAR\nDOCKER_UNSET_VAR\n  DOCKER_HOST_VAR"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"DOCKER_HOST_VAR": "from host"}, env)
+}
+
+func TestParseDockerEnvFile_errors(t *testing.T) {
+	errorFormats := []struct {
+		in  string
+		err string
+	}{
+		{"FOO=1\n=bar", "2:1: line `=bar` doesn't match format"},
+		{"FOO BAR=1", "1:4: line `FOO BAR=1` doesn't match format"},
+		{"  FOO\tBAR", "1:6: line `FOO\tBAR` doesn't match format"},
+		{"FOO=\xff", "1:1: line `FOO=\xff` doesn't match format"},
+	}
+
+	for _, tt := range errorFormats {
+		env, err := gotenv.ParseDockerEnvFile(strings.NewReader(tt.in))
+		assert.ErrorIs(t, err, gotenv.ErrInvalidLine, tt.in)
+		assert.EqualError(t, err, tt.err, tt.in)
+		assert.NotContains(t, env, "BAR", tt.in)
+	}
+}
+
+func TestMarshalDockerEnvFile(t *testing.T) {
+	env := gotenv.Env{"B": `"quoted" $VAR`, "A": "a=b # c", "C": "", "D": " spaced "}
+	out, err := gotenv.MarshalDockerEnvFile(env)
+	assert.Nil(t, err)
+	assert.Equal(t, "A=a=b # c\nB=\"quoted\" $VAR\nC=\nD= spaced ", out)
+
+	// round trip
+	back, err := gotenv.ParseDockerEnvFile(strings.NewReader(out))
+	assert.Nil(t, err)
+	assert.Equal(t, env, back)
+}
+
+func TestMarshalDockerEnvFile_unrepresentable(t *testing.T) {
+	tests := []struct {
+		env gotenv.Env
+		err string
+	}{
+		{gotenv.Env{"A": "multi\nline"}, "can't be represented: A: value holding a line break"},
+		{gotenv.Env{"A": "a\rb"}, "can't be represented: A: value holding a line break"},
+		{gotenv.Env{"A": "a\x00b"}, "can't be represented: A: value holding a NUL character"},
+		{gotenv.Env{"": "a"}, "can't be represented: : empty name"},
+		{gotenv.Env{"#A": "a"}, "can't be represented: #A: name starting with a hash"},
+		{gotenv.Env{"A B": "a"}, "can't be represented: A B: name holding a blank"},
+		{gotenv.Env{"A=B": "a"}, "can't be represented: A=B: name holding an equal sign or a NUL character"},
+		{gotenv.Env{"A": "\xff"}, "can't be represented: A: invalid UTF-8"},
+	}
+
+	for _, tt := range tests {
+		out, err := gotenv.MarshalDockerEnvFile(tt.env)
+		assert.True(t, errors.Is(err, gotenv.ErrUnrepresentable), tt.err)
+		assert.EqualError(t, err, tt.err)
+		assert.Equal(t, "", out)
+	}
+}
+
+func TestWriteDockerEnvFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sub", "docker.env")
+	env := gotenv.Env{"FOO": `"bar"`, "BAZ": "1"}
+
+	err := gotenv.WriteDockerEnvFile(env, filename)
+	assert.Nil(t, err)
+
+	data, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "BAZ=1\nFOO=\"bar\"\n", string(data))
+
+	back, err := gotenv.ReadDockerEnvFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, env, back)
+
+	err = gotenv.WriteDockerEnvFile(gotenv.Env{"FOO": "a\nb"}, filename)
+	assert.ErrorIs(t, err, gotenv.ErrUnrepresentable)
+
+	err = os.WriteFile(filename, []byte("FOO=1\nB AR=2"), 0o644)
+	assert.Nil(t, err)
+	_, err = gotenv.ReadDockerEnvFile(filename)
+	assert.EqualError(t, err, filename+":2:2: line `B AR=2` doesn't match format")
+
+	_, err = gotenv.ReadDockerEnvFile(filepath.Join(t.TempDir(), "missing.env"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
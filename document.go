@@ -0,0 +1,469 @@
+package gotenv
+
+import (
+	"io"
+	"iter"
+	"os"
+	"strings"
+)
+
+// Document is a lossless representation of an env file.
+// Besides the variables, it keeps comments, blank lines, `export` keywords, quoting styles and separators,
+// so that a file can be edited programmatically and written back with the untouched lines reproduced byte for byte.
+//
+// Values handled by a Document are literal: they are unquoted and unescaped but variables are not expanded.
+// Files encoded in UTF-16 are written back as UTF-8.
+type Document struct {
+	bom   []byte
+	eol   string
+	lines []*docLine
+}
+
+// docLine is a line of a Document, or several lines for multi-line values.
+type docLine struct {
+	raw string // the text of the line, including its line break
+
+	// entries and `export KEY` lines only
+	entry    bool // false for `export KEY` lines
+	indent   string
+	export   string
+	key      string
+	trailing string // whitespace and comment after the value or the key
+	eol      string
+
+	// entries only
+	sep      string
+	src      string // the value as written, quotes included, or the opening delimiter of a heredoc
+	value    string // the literal value
+	quote    byte
+	verbatim bool   // variables are never expanded, see isRaw
+	heredoc  string // the lines following the first one of a heredoc, up to its closing delimiter
+}
+
+// declares reports whether the line is an entry or an `export KEY` line of the variable key,
+// comments and blank lines having no key.
+func (l *docLine) declares(key string) bool {
+	return (l.entry || l.export != "") && l.key == key
+}
+
+// render rebuilds the raw text of the line from its parts.
+func (l *docLine) render() {
+	l.raw = l.indent + l.export + l.key + l.sep + l.src + l.trailing + l.heredoc + l.eol
+}
+
+// setValue sets the literal value of an entry, keeping its quoting style when possible.
+func (l *docLine) setValue(val string) {
+	l.value = val
+	if l.heredoc != "" {
+		if body, ok := heredocBody(val, l.src, l.verbatim, l.heredoc[:eolSize(l.heredoc, 0)]); ok {
+			l.heredoc = body
+			l.render()
+			return
+		}
+		l.heredoc = ""
+	}
+	l.src, l.quote = quoteValue(val, l.quote, l.verbatim)
+	l.render()
+}
+
+// ParseDocument parses r into a Document.
+// It returns an error if there are any invalid lines.
+func ParseDocument(r io.Reader) (*Document, error) {
+	z, bom, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(z)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{eol: "\n"}
+	if string(bom) == string(bomUTF8) {
+		doc.bom = bom
+	}
+
+	lines := splitRawLines(string(data))
+	if len(lines) > 0 {
+		if _, eol := cutEOL(lines[0]); eol != "" {
+			doc.eol = eol
+		}
+	}
+
+	lx := NewParser().newLexer()
+	defined := NewOrderedEnv()
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		raw := lines[i]
+		first, eol := cutEOL(raw)
+
+		line := strings.TrimSpace(first)
+		if line == "" || line[0] == '#' {
+			doc.lines = append(doc.lines, &docLine{raw: raw})
+			continue
+		}
+
+		lx.lex(first)
+		for lx.open && i+1 < len(lines) {
+			i++
+			raw += lines[i]
+			l, next := cutEOL(lines[i])
+			lx.feed(eol + l)
+			eol = next
+		}
+		lx.end()
+		if lx.open {
+			return nil, &ParseError{Line: start, Column: lx.line.valuePos + 1, Text: line, Err: lx.missing()}
+		}
+
+		t, err := lx.result()
+		if err != nil {
+			err.(*ParseError).Line = start
+			return nil, err
+		}
+		dl := &docLine{
+			raw:    raw,
+			indent: t.text[:t.start],
+			export: t.text[t.start:t.keyPos],
+			key:    t.key(),
+			eol:    eol,
+		}
+		if !t.entry {
+			// a valid `export KEY` line
+			if _, ok := defined.Get(dl.key); !ok {
+				return nil, &ParseError{Line: start, Column: t.keyPos + 1, Text: line, Err: ErrUnsetExport}
+			}
+			dl.trailing = t.text[t.keyEnd:]
+			doc.lines = append(doc.lines, dl)
+			continue
+		}
+
+		dl.entry = true
+		dl.sep = t.text[t.keyEnd:t.valuePos]
+		dl.src = t.value()
+		dl.trailing = t.text[t.valueEnd:]
+		dl.verbatim = isRaw(t.comment())
+		if t.heredoc {
+			dl.trailing = t.text[t.valueEnd:t.bodyPos]
+			dl.heredoc = t.text[t.bodyPos:]
+			dl.value, dl.quote = heredocLiteral(t.body(), t.quote, dl.verbatim), t.quote
+		} else {
+			dl.value, dl.quote = literal(dl.src, dl.verbatim)
+		}
+		defined.Set(dl.key, dl.value)
+
+		doc.lines = append(doc.lines, dl)
+	}
+
+	return doc, nil
+}
+
+// ReadDocument parses a file into a Document.
+// It returns an error if there are any invalid lines.
+func ReadDocument(filename string) (*Document, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc, err := ParseDocument(f)
+	return doc, withFilename(err, filename)
+}
+
+// Get returns the literal value of the variable and whether it's present.
+// When a variable is declared more than once, the last declaration wins.
+func (d *Document) Get(key string) (string, bool) {
+	if l := d.last(key); l != nil {
+		return l.value, true
+	}
+	return "", false
+}
+
+// Keys returns the names of the variables in declaration order.
+func (d *Document) Keys() []string {
+	var keys []string
+	for k := range d.All() {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// All returns an iterator over the variables and their literal values in declaration order.
+func (d *Document) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		seen := make(map[string]bool)
+		for _, l := range d.lines {
+			if !l.entry || seen[l.key] {
+				continue
+			}
+			seen[l.key] = true
+			if val, _ := d.Get(l.key); !yield(l.key, val) {
+				return
+			}
+		}
+	}
+}
+
+// Set sets the literal value of the variable.
+// The last declaration of the variable is updated in place, keeping its quoting style when the value allows it.
+// When the variable is not present, it's appended at the end of the document.
+// It returns false, leaving the document unchanged, when key is not a valid key.
+func (d *Document) Set(key, val string) bool {
+	if !isKey(key) {
+		return false
+	}
+
+	if l := d.last(key); l != nil {
+		l.setValue(val)
+		return true
+	}
+	d.insert(len(d.lines), key, val)
+	return true
+}
+
+// InsertAfter adds the variable on a new line right after the last declaration of the variable after.
+// It returns false, leaving the document unchanged, when after is not present or key is not a valid key.
+func (d *Document) InsertAfter(after, key, val string) bool {
+	if !isKey(key) {
+		return false
+	}
+
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if d.lines[i].entry && d.lines[i].key == after {
+			d.insert(i+1, key, val)
+			return true
+		}
+	}
+	return false
+}
+
+// Delete removes every declaration of the variable, along with its `export KEY` lines.
+// It returns false when the variable is not present.
+func (d *Document) Delete(key string) bool {
+	found := false
+	lines := d.lines[:0]
+	for _, l := range d.lines {
+		if l.declares(key) {
+			found = found || l.entry
+			continue
+		}
+		lines = append(lines, l)
+	}
+	d.lines = lines
+	return found
+}
+
+// Rename renames every declaration of the variable oldKey, along with its `export KEY` lines.
+// It returns false, leaving the document unchanged, when oldKey is not present or newKey is not a valid key.
+func (d *Document) Rename(oldKey, newKey string) bool {
+	if !isKey(newKey) {
+		return false
+	}
+
+	found := false
+	for _, l := range d.lines {
+		if !l.declares(oldKey) {
+			continue
+		}
+		l.key = newKey
+		l.render()
+		found = found || l.entry
+	}
+	return found
+}
+
+// WriteTo writes the document to w. It implements io.WriterTo.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	sb.Write(d.bom)
+	for _, l := range d.lines {
+		sb.WriteString(l.raw)
+	}
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// String returns the content of the document.
+func (d *Document) String() string {
+	var sb strings.Builder
+	_, _ = d.WriteTo(&sb)
+	return sb.String()
+}
+
+func (d *Document) last(key string) *docLine {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if l := d.lines[i]; l.entry && l.key == key {
+			return l
+		}
+	}
+	return nil
+}
+
+// isKey reports whether s is a valid key of an env file.
+func isKey(s string) bool {
+	sx := dialects[DialectGotenv].syntax
+	for i := 0; i < len(s); i++ {
+		if !sx.isKeyChar(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// insert adds a new entry at the index i.
+func (d *Document) insert(i int, key, val string) {
+	// make sure the previous line is terminated
+	if i > 0 {
+		prev := d.lines[i-1]
+		if prev.entry && prev.heredoc == "" && prev.quote == 0 && continued(prev.src) {
+			// the value would be continued on the new line
+			prev.setValue(prev.value)
+		}
+		if !strings.HasSuffix(prev.raw, "\n") && !strings.HasSuffix(prev.raw, "\r") {
+			prev.raw += d.eol
+			prev.eol = d.eol
+		}
+	}
+
+	l := &docLine{key: key, entry: true, sep: "=", eol: d.eol}
+	l.setValue(val)
+
+	d.lines = append(d.lines, nil)
+	copy(d.lines[i+1:], d.lines[i:])
+	d.lines[i] = l
+}
+
+// literal returns the literal value written as src, along with its quote character.
+// Escape sequences of double quoted values are decoded, unknown ones being kept as they are,
+// and the lines of continued unquoted values are joined.
+// Escaped dollar signs are unescaped unless the value is verbatim.
+func literal(src string, verbatim bool) (string, byte) {
+	// line breaks within a value are read as LF
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+
+	val, quote := unquote(src, dialects[DialectGotenv].quotes)
+	switch {
+	case quote == '"':
+		val, _ = escapesFull.unescape(val, !verbatim)
+	case quote == 0:
+		val = joinLines(val)
+		if !verbatim {
+			val = strings.ReplaceAll(val, `\$`, "$")
+		}
+	case quote == '`' && !verbatim:
+		val = strings.ReplaceAll(val, `\$`, "$")
+	}
+	return val, quote
+}
+
+// heredocLiteral returns the literal value of a heredoc whose delimiter is quoted with quote.
+// Escaped dollar signs are unescaped unless the delimiter is quoted or the value is verbatim.
+func heredocLiteral(body string, quote byte, verbatim bool) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\r", "\n")
+	if quote == 0 && !verbatim {
+		body = strings.ReplaceAll(body, `\$`, "$")
+	}
+	return body
+}
+
+// heredocBody returns how to write the literal value val as the lines of a heredoc opened by src, like <<EOF,
+// separated by eol. It reports false when the value can't be written that way.
+func heredocBody(val, src string, verbatim bool, eol string) (string, bool) {
+	delim := strings.Trim(src[len("<<"):], `'"`)
+	if strings.ContainsRune(val, '\r') {
+		return "", false
+	}
+	for _, line := range strings.Split(val, "\n") {
+		if strings.TrimFunc(line, isSpace) == delim {
+			return "", false
+		}
+	}
+
+	if val == "" {
+		return eol + delim, true
+	}
+	if src[len("<<")] != '\'' && src[len("<<")] != '"' && !verbatim {
+		val = strings.ReplaceAll(val, "$", `\$`)
+	}
+	return eol + strings.ReplaceAll(val, "\n", eol) + eol + delim, true
+}
+
+var (
+	doubleQuoteEscaper         = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+	verbatimDoubleQuoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+)
+
+// quoteValue returns how to write the literal value val, using the quote character when possible.
+// It falls back to double quotes, which can represent any value, like the ones holding a CR
+// which would be read back as a LF within single quotes or backticks.
+// Dollar signs of verbatim values are not escaped.
+func quoteValue(val string, quote byte, verbatim bool) (string, byte) {
+	if strings.ContainsRune(val, '\r') {
+		quote = '"'
+	}
+
+	switch {
+	case quote == '\'' && !strings.Contains(val, "'"):
+		return "'" + val + "'", quote
+	case quote == '`' && !strings.Contains(val, "`") && verbatim:
+		return "`" + val + "`", quote
+	case quote == '`' && !strings.Contains(val, "`"):
+		return "`" + strings.ReplaceAll(val, "$", `\$`) + "`", quote
+	case quote == 0 && isBare(val, verbatim):
+		return val, quote
+	case verbatim:
+		return `"` + verbatimDoubleQuoteEscaper.Replace(val) + `"`, '"'
+	}
+	return `"` + doubleQuoteEscaper.Replace(val) + `"`, '"'
+}
+
+// isBare reports whether val can be written without quotes.
+func isBare(val string, verbatim bool) bool {
+	if val == "" {
+		return true
+	}
+	if isSpace(rune(val[0])) || isSpace(rune(val[len(val)-1])) || val[0] == '"' || val[0] == '\'' || val[0] == '`' {
+		return false
+	}
+	if strings.HasPrefix(val, "<<") {
+		// read as the opening of a heredoc
+		return false
+	}
+	if !verbatim && strings.Contains(val, "$") {
+		return false
+	}
+	return !strings.ContainsAny(val, "#\\\n\r")
+}
+
+// splitRawLines splits s into lines, keeping their line break (CR, LF or CRLF).
+func splitRawLines(s string) []string {
+	var lines []string
+	for s != "" {
+		idx := strings.IndexAny(s, "\r\n")
+		if idx < 0 {
+			lines = append(lines, s)
+			break
+		}
+		eol := idx + 1
+		if s[idx] == '\r' && eol < len(s) && s[eol] == '\n' {
+			eol++
+		}
+		lines = append(lines, s[:eol])
+		s = s[eol:]
+	}
+	return lines
+}
+
+// cutEOL splits the line break off the end of the line.
+func cutEOL(line string) (string, string) {
+	text := strings.TrimRight(line, "\r\n")
+	return text, line[len(text):]
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f'
+}
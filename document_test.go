@@ -0,0 +1,309 @@
+package gotenv_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+const document = `# database
+export DB_HOST = localhost
+DB_PORT: 5432
+DB_USER='admin' # the user
+DB_PASS="s3cr\"et\$"
+
+  VERSION=1.0.0   # bumped by CI
+export DB_HOST
+CERT="-----BEGIN-----
+abc
+-----END-----"
+`
+
+func TestParseDocument_roundTrip(t *testing.T) {
+	for _, in := range []string{document, strings.ReplaceAll(document, "\n", "\r\n"), "FOO=bar", ""} {
+		doc, err := gotenv.ParseDocument(strings.NewReader(in))
+		assert.Nil(t, err)
+		assert.Equal(t, in, doc.String())
+	}
+
+	for _, tt := range fixtures {
+		raw, err := os.ReadFile(tt.filename)
+		assert.Nil(t, err)
+
+		doc, err := gotenv.ReadDocument(tt.filename)
+		assert.Nil(t, err)
+		assert.Equal(t, string(raw), doc.String())
+	}
+}
+
+func TestParseDocument_values(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader(document))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"DB_HOST", "DB_PORT", "DB_USER", "DB_PASS", "VERSION", "CERT"}, doc.Keys())
+
+	for key, val := range map[string]string{
+		"DB_HOST": "localhost",
+		"DB_PORT": "5432",
+		"DB_USER": "admin",
+		"DB_PASS": `s3cr"et$`,
+		"VERSION": "1.0.0",
+		"CERT":    "-----BEGIN-----\nabc\n-----END-----",
+	} {
+		actual, ok := doc.Get(key)
+		assert.True(t, ok)
+		assert.Equal(t, val, actual, key)
+	}
+
+	_, ok := doc.Get("NOPE")
+	assert.False(t, ok)
+}
+
+func TestDocument_edit(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader(document))
+	assert.Nil(t, err)
+
+	doc.Set("VERSION", "1.1.0")
+	doc.Set("DB_USER", "it's me")
+	doc.Set("DB_PORT", "6543")
+	doc.Set("NEW", "$HOME")
+	assert.True(t, doc.Rename("DB_HOST", "DATABASE_HOST"))
+	assert.True(t, doc.InsertAfter("DB_PORT", "DB_NAME", "app"))
+	assert.True(t, doc.Delete("CERT"))
+	assert.False(t, doc.Delete("CERT"))
+	assert.False(t, doc.Rename("NOPE", "NEVER"))
+	assert.False(t, doc.InsertAfter("NOPE", "NEVER", ""))
+
+	expected := `# database
+export DATABASE_HOST = localhost
+DB_PORT: 6543
+DB_NAME=app
+DB_USER="it's me" # the user
+DB_PASS="s3cr\"et\$"
+
+  VERSION=1.1.0   # bumped by CI
+export DATABASE_HOST
+NEW="\$HOME"
+`
+	assert.Equal(t, expected, doc.String())
+
+	env, err := gotenv.Unmarshal(doc.String())
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{
+		"DATABASE_HOST": "localhost",
+		"DB_PORT":       "6543",
+		"DB_NAME":       "app",
+		"DB_USER":       "it's me",
+		"DB_PASS":       `s3cr"et$`,
+		"VERSION":       "1.1.0",
+		"NEW":           "$HOME",
+	}, env)
+}
+
+func TestDocument_editComments(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader(document))
+	assert.Nil(t, err)
+
+	// comments and blank lines are not declarations of an empty key
+	assert.False(t, doc.Delete(""))
+	assert.False(t, doc.Rename("", "Z"))
+	assert.Equal(t, document, doc.String())
+}
+
+func TestDocument_renameInvalidKey(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader(document))
+	assert.Nil(t, err)
+
+	for _, key := range []string{"", "bad key=", "A#", "A=B", "A\nB"} {
+		assert.False(t, doc.Rename("DB_HOST", key), key)
+	}
+	assert.Equal(t, document, doc.String())
+
+	assert.True(t, doc.Rename("DB_HOST", "db.host"))
+	val, ok := doc.Get("db.host")
+	assert.True(t, ok)
+	assert.Equal(t, "localhost", val)
+}
+
+func TestDocument_setInvalidKey(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader(document))
+	assert.Nil(t, err)
+
+	for _, key := range []string{"", "B C", "A#", "A=B", "A\nB"} {
+		assert.False(t, doc.Set(key, "1"), key)
+		assert.False(t, doc.InsertAfter("DB_PORT", key, "1"), key)
+	}
+	assert.Equal(t, document, doc.String())
+
+	assert.True(t, doc.Set("db.name", "app"))
+	assert.True(t, doc.InsertAfter("DB_PORT", "db.port", "1"))
+	_, err = gotenv.ParseDocument(strings.NewReader(doc.String()))
+	assert.Nil(t, err)
+}
+
+func TestDocument_setRoundTrip(t *testing.T) {
+	values := []string{"", "plain", " spaced ", "it's", `"quoted"`, "`ticked`", "$HOME", `C:\tmp\`, "a # b",
+		"multi\nline", "cr\rlf", "crlf\r\n", "#", "\t", "<<EOF", "<<-EOF", "<<'EOF'"}
+
+	for _, src := range []string{"A=x\n", "A='x'\n", "A=`x`\n", "A=\"x\"\n", "A=x # gotenv:raw\n", "A='x' # gotenv:raw\n"} {
+		for _, val := range values {
+			doc, err := gotenv.ParseDocument(strings.NewReader(src))
+			assert.Nil(t, err)
+			assert.True(t, doc.Set("A", val))
+			assert.True(t, doc.Set("B", val))
+
+			out, err := gotenv.ParseDocument(strings.NewReader(doc.String()))
+			if assert.Nil(t, err, "%q %q", src, val) {
+				for _, key := range []string{"A", "B"} {
+					actual, _ := out.Get(key)
+					assert.Equal(t, val, actual, "%q %q", src, val)
+				}
+			}
+		}
+	}
+}
+
+func TestDocument_appendWithoutTrailingNewline(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader("A=1\r\nB=2"))
+	assert.Nil(t, err)
+
+	doc.Set("C", "multi\nline")
+	assert.Equal(t, "A=1\r\nB=2\r\nC=\"multi\\nline\"\r\n", doc.String())
+}
+
+func TestDocument_raw(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader("A=p@$$ # gotenv:raw\nB=\"x\\$y\" # gotenv:raw\n"))
+	assert.Nil(t, err)
+
+	val, _ := doc.Get("A")
+	assert.Equal(t, "p@$$", val)
+	val, _ = doc.Get("B")
+	assert.Equal(t, `x\$y`, val)
+
+	doc.Set("A", "n3w$ecret")
+	doc.Set("B", "it's $HOME ")
+	assert.Equal(t, "A=n3w$ecret # gotenv:raw\nB=\"it's $HOME \" # gotenv:raw\n", doc.String())
+
+	env, err := gotenv.Unmarshal(doc.String())
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": "n3w$ecret", "B": "it's $HOME "}, env)
+}
+
+func TestDocument_escapes(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader(`A="a\tb\u00e9\q"` + "\nB=c\\td\n"))
+	assert.Nil(t, err)
+
+	val, _ := doc.Get("A")
+	assert.Equal(t, "a\tb\u00e9\\q", val)
+	val, _ = doc.Get("B")
+	assert.Equal(t, `c\td`, val)
+}
+
+func TestDocument_continuedLines(t *testing.T) {
+	in := "A=a \\\r\n  b # c\r\nB=1\r\n"
+	doc, err := gotenv.ParseDocument(strings.NewReader(in))
+	assert.Nil(t, err)
+	assert.Equal(t, in, doc.String())
+
+	val, _ := doc.Get("A")
+	assert.Equal(t, "a   b", val)
+
+	doc.Set("A", "x")
+	assert.Equal(t, "A=x # c\r\nB=1\r\n", doc.String())
+}
+
+func TestDocument_insertAfterContinuedLine(t *testing.T) {
+	for _, in := range []string{"DIR=C:\\tmp\\", "DIR=C:\\tmp\\\n", "DIR=C:\\tmp\\\r\n"} {
+		doc, err := gotenv.ParseDocument(strings.NewReader(in))
+		assert.Nil(t, err)
+		assert.True(t, doc.Set("B", "1"))
+
+		env, err := gotenv.Unmarshal(doc.String())
+		assert.Nil(t, err, "%q", in)
+		assert.Equal(t, gotenv.Env{"DIR": `C:\tmp`, "B": "1"}, env, "%q", in)
+
+		doc, err = gotenv.ParseDocument(strings.NewReader(in))
+		assert.Nil(t, err)
+		assert.True(t, doc.InsertAfter("DIR", "B", "1"))
+
+		env, err = gotenv.Unmarshal(doc.String())
+		assert.Nil(t, err, "%q", in)
+		assert.Equal(t, gotenv.Env{"DIR": `C:\tmp`, "B": "1"}, env, "%q", in)
+	}
+}
+
+func TestDocument_backticks(t *testing.T) {
+	doc, err := gotenv.ParseDocument(strings.NewReader("A=`it's \"a\" \\$b`\n"))
+	assert.Nil(t, err)
+
+	val, _ := doc.Get("A")
+	assert.Equal(t, `it's "a" $b`, val)
+
+	doc.Set("A", `'$b'`)
+	assert.Equal(t, "A=`'\\$b'`\n", doc.String())
+	doc.Set("A", "`")
+	assert.Equal(t, "A=\"`\"\n", doc.String())
+}
+
+func TestDocument_heredoc(t *testing.T) {
+	in := "A=<<EOF # c\r\na $b\r\n\\$c\r\nEOF\r\nB=<<'EOF'\r\n$b\r\nEOF\r\nC=1\r\n"
+	doc, err := gotenv.ParseDocument(strings.NewReader(in))
+	assert.Nil(t, err)
+	assert.Equal(t, in, doc.String())
+
+	val, _ := doc.Get("A")
+	assert.Equal(t, "a $b\n$c", val)
+	val, _ = doc.Get("B")
+	assert.Equal(t, "$b", val)
+
+	doc.Set("A", "x\n$y")
+	doc.Set("B", "$x\n\n")
+	doc.Set("C", "1\nEOF")
+	assert.Equal(t, "A=<<EOF # c\r\nx\r\n\\$y\r\nEOF\r\nB=<<'EOF'\r\n$x\r\n\r\n\r\nEOF\r\nC=\"1\\nEOF\"\r\n", doc.String())
+
+	doc.Set("A", "EOF")
+	assert.Equal(t, "A=EOF # c\r\n", strings.SplitAfter(doc.String(), "\r\n")[0])
+
+	out, err := gotenv.ParseDocument(strings.NewReader(doc.String()))
+	assert.Nil(t, err)
+	for key, val := range doc.All() {
+		actual, _ := out.Get(key)
+		assert.Equal(t, val, actual, key)
+	}
+}
+
+func TestDocument_WriteTo(t *testing.T) {
+	doc, err := gotenv.ReadDocument("fixtures/utf8_bom.env")
+	assert.Nil(t, err)
+
+	doc.Set("BOM", "still UTF-8")
+
+	filename := filepath.Join(t.TempDir(), ".env")
+	f, err := os.Create(filename)
+	assert.Nil(t, err)
+	_, err = doc.WriteTo(f)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	raw, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "\xEF\xBB\xBFBOM=still UTF-8", string(raw))
+}
+
+func TestParseDocument_errors(t *testing.T) {
+	_, err := gotenv.ParseDocument(strings.NewReader("A=1\n  lol$wut"))
+	assert.EqualError(t, err, "2:6: line `lol$wut` doesn't match format")
+
+	_, err = gotenv.ParseDocument(strings.NewReader("A=\"1\nB=2"))
+	assert.ErrorIs(t, err, gotenv.ErrMissingQuote)
+
+	_, err = gotenv.ReadDocument(".env.invalid")
+	var pe *gotenv.ParseError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.Equal(t, ".env.invalid", pe.Filename)
+	}
+}
@@ -0,0 +1,113 @@
+package gotenv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Causes of a ParseError, they can be checked with errors.Is.
+var (
+	// ErrInvalidLine is returned when a line doesn't match the env file format.
+	ErrInvalidLine = errors.New("doesn't match format")
+
+	// ErrMissingQuote is returned when a quoted value is never closed.
+	ErrMissingQuote = errors.New("has missing quotes")
+
+	// ErrMissingDelimiter is returned when the closing delimiter of a heredoc value is never found.
+	ErrMissingDelimiter = errors.New("has a missing heredoc delimiter")
+
+	// ErrUnsetExport is returned when an export line refers to a variable that is not set.
+	ErrUnsetExport = errors.New("has an unset variable")
+
+	// ErrRequiredVar is returned when a variable required by ${VAR:?message} or ${VAR?message} is not set.
+	ErrRequiredVar = errors.New("has an unset required variable")
+
+	// ErrUnknownEscape is returned when a double quoted value holds an unknown escape sequence, like \q.
+	ErrUnknownEscape = errors.New("has an unknown escape sequence")
+
+	// ErrLineTooLong is returned when a line, or a value spanning several lines, exceeds the maximum size of a Parser.
+	ErrLineTooLong = errors.New("exceeds the maximum size")
+
+	// ErrUndefinedVar is returned when a value refers to a variable which is defined neither earlier in the file
+	// nor by the lookup function, with the WithStrictExpansion option.
+	ErrUndefinedVar = errors.New("has an undefined variable")
+)
+
+// ErrUnrepresentable is returned when a variable can't be written in the format of another tool, like a Docker env file,
+// or set as an environment variable, like a value holding a NUL character.
+var ErrUnrepresentable = errors.New("can't be represented")
+
+// CycleError is the cause of a ParseError when variables refer to each other with the WithForwardReferences option.
+type CycleError struct {
+	Path []string // names of the variables of the cycle, the first one being repeated at the end
+}
+
+func (e *CycleError) Error() string {
+	return "has a reference cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// ParseError describes an invalid line found while parsing an env file.
+type ParseError struct {
+	Filename string // name of the file, empty when parsing an io.Reader
+	Line     int    // 1-based line number
+	Column   int    // 1-based column, counted in bytes
+	Text     string // the offending text
+	Err      error  // the cause, such as ErrInvalidLine
+}
+
+func (e *ParseError) Error() string {
+	pos := fmt.Sprintf("%d:%d", e.Line, e.Column)
+	if e.Filename != "" {
+		pos = e.Filename + ":" + pos
+	}
+
+	return fmt.Sprintf("%s: line `%s` %v", pos, e.Text, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// varError is the error of a variable, referenced in a value or written to a file.
+type varError struct {
+	name string
+	msg  string
+	err  error
+}
+
+func (e *varError) Error() string {
+	if e.msg == "" {
+		return fmt.Sprintf("%v: %s", e.err, e.name)
+	}
+
+	return fmt.Sprintf("%v: %s: %s", e.err, e.name, e.msg)
+}
+
+func (e *varError) Unwrap() error {
+	return e.err
+}
+
+// withFilename records the filename on every ParseError found in err.
+func withFilename(err error, filename string) error {
+	for _, e := range unjoin(err) {
+		var pe *ParseError
+		if errors.As(e, &pe) {
+			pe.Filename = filename
+		}
+	}
+
+	return err
+}
+
+// unjoin returns the errors joined in err by errors.Join.
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+
+	return []error{err}
+}
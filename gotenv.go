@@ -4,11 +4,13 @@ package gotenv
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,14 +19,6 @@ import (
 	"golang.org/x/text/transform"
 )
 
-const (
-	// Pattern for detecting valid line format
-	linePattern = `\A\s*(?:export\s+)?([\w\.]+)(?:\s*=\s*|:\s+?)('(?:\'|[^'])*'|"(?:\"|[^"])*"|[^#\n]+)?\s*(?:\s*\#.*)?\z`
-
-	// Pattern for detecting valid variable within a value
-	variablePattern = `(\\)?(\$)(\{?([A-Z0-9_]+)?\}?)`
-)
-
 // Byte order mark character
 var (
 	bomUTF8    = []byte("\xEF\xBB\xBF")
@@ -47,6 +41,28 @@ func OverLoad(filenames ...string) error {
 	return loadenv(true, filenames...)
 }
 
+// LoadOptional is like Load but it skips the files which don't exist, and returns their names.
+// Other errors, like a denied permission or an invalid line, are still returned.
+func LoadOptional(filenames ...string) ([]string, error) {
+	return NewParser().LoadOptional(filenames...)
+}
+
+// OverLoadOptional is like OverLoad but it skips the files which don't exist, and returns their names.
+func OverLoadOptional(filenames ...string) ([]string, error) {
+	return NewParser(WithOverride(true)).LoadOptional(filenames...)
+}
+
+// LoadFS is like Load but it reads the files from fsys, such as an embed.FS.
+// When it's called with no filename, it loads the `.env` file at the root of fsys.
+func LoadFS(fsys fs.FS, filenames ...string) error {
+	return NewParser().LoadFS(fsys, filenames...)
+}
+
+// OverLoadFS is like OverLoad but it reads the files from fsys, such as an embed.FS.
+func OverLoadFS(fsys fs.FS, filenames ...string) error {
+	return NewParser(WithOverride(true)).LoadFS(fsys, filenames...)
+}
+
 // Must is wrapper function that will panic when supplied function returns an error.
 func Must(fn func(filenames ...string) error, filenames ...string) {
 	if err := fn(filenames...); err != nil {
@@ -65,38 +81,12 @@ func OverApply(r io.Reader) error {
 }
 
 func loadenv(override bool, filenames ...string) error {
-	if len(filenames) == 0 {
-		filenames = []string{".env"}
-	}
-
-	for _, filename := range filenames {
-		f, err := os.Open(filename)
-		if err != nil {
-			return err
-		}
-
-		err = parset(f, override)
-		f.Close()
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return NewParser(WithOverride(override)).Load(filenames...)
 }
 
 // parse and set :)
 func parset(r io.Reader, override bool) error {
-	env, err := strictParse(r, override)
-	if err != nil {
-		return err
-	}
-
-	for key, val := range env {
-		setenv(key, val, override)
-	}
-
-	return nil
+	return NewParser(WithOverride(override)).Apply(r)
 }
 
 func setenv(key, val string, override bool) {
@@ -113,34 +103,54 @@ func setenv(key, val string, override bool) {
 // It expands the value of a variable from the environment variable but does not set the value to the environment itself.
 // This function is skipping any invalid lines and only processing the valid one.
 func Parse(r io.Reader) Env {
-	env, _ := strictParse(r, false)
+	env, _ := NewParser(WithStrict(false)).Parse(r)
 	return env
 }
 
+// ParseWithWarnings is like Parse but it also returns the problems found on the skipped lines as warnings,
+// along with the ones which don't prevent a line from being parsed, like the references to undefined variables
+// holding ErrUndefinedVar and the unknown escape sequences holding ErrUnknownEscape, in the order of the lines.
+func ParseWithWarnings(r io.Reader) (Env, []error) {
+	var warnings []error
+	env, err := NewParser(WithStrict(false), WithWarnings(func(w error) {
+		warnings = append(warnings, w)
+	})).Parse(r)
+
+	warnings = append(warnings, unjoin(err)...)
+	sortByLine(warnings)
+	return env, warnings
+}
+
 // StrictParse is a function to parse line by line any io.Reader supplied and returns the valid Env key/value pair of valid variables.
 // It expands the value of a variable from the environment variable but does not set the value to the environment itself.
 // This function is returning an error if there are any invalid lines.
 func StrictParse(r io.Reader) (Env, error) {
-	return strictParse(r, false)
+	return NewParser().Parse(r)
+}
+
+// StrictParseAll is like StrictParse but it doesn't stop at the first invalid line.
+// It skips every invalid line and returns all of them as ParseError values joined with errors.Join.
+func StrictParseAll(r io.Reader) (Env, error) {
+	return NewParser(WithStrict(false)).Parse(r)
 }
 
 // Read is a function to parse a file line by line and returns the valid Env key/value pair of valid variables.
 // It expands the value of a variable from the environment variable but does not set the value to the environment itself.
 // This function is skipping any invalid lines and only processing the valid one.
 func Read(filename string) (Env, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	return strictParse(f, false)
+	return NewParser().ParseFile(filename)
+}
+
+// ReadFS is like Read but it reads the file from fsys, such as an embed.FS.
+func ReadFS(fsys fs.FS, filename string) (Env, error) {
+	return NewParser().ParseFS(fsys, filename)
 }
 
 // Unmarshal reads a string line by line and returns the valid Env key/value pair of valid variables.
 // It expands the value of a variable from the environment variable but does not set the value to the environment itself.
 // This function is returning an error if there are any invalid lines.
 func Unmarshal(str string) (Env, error) {
-	return strictParse(strings.NewReader(str), false)
+	return NewParser().Parse(strings.NewReader(str))
 }
 
 // Marshal outputs the given environment as a env file.
@@ -148,22 +158,30 @@ func Unmarshal(str string) (Env, error) {
 func Marshal(env Env) (string, error) {
 	lines := make([]string, 0, len(env))
 	for k, v := range env {
-		if d, err := strconv.Atoi(v); err == nil {
-			lines = append(lines, fmt.Sprintf(`%s=%d`, k, d))
-		} else {
-			lines = append(lines, fmt.Sprintf(`%s=%q`, k, v))
-		}
+		lines = append(lines, marshalLine(k, v))
 	}
 	sort.Strings(lines)
 	return strings.Join(lines, "\n"), nil
 }
 
+func marshalLine(key, val string) string {
+	if d, err := strconv.Atoi(val); err == nil {
+		return fmt.Sprintf(`%s=%d`, key, d)
+	}
+	return fmt.Sprintf(`%s=%q`, key, val)
+}
+
 // Write serializes the given environment and writes it to a file
 func Write(env Env, filename string) error {
 	content, err := Marshal(env)
 	if err != nil {
 		return err
 	}
+	return writeFile(filename, content)
+}
+
+// writeFile writes the content of an env file, followed by a line break, to the file.
+func writeFile(filename, content string) error {
 	// ensure the path exists
 	if err := os.MkdirAll(filepath.Dir(filename), 0o775); err != nil {
 		return err
@@ -208,9 +226,62 @@ func splitLines(data []byte, atEOF bool) (advance int, token []byte, err error)
 	return eol, data[:idx], nil
 }
 
-func strictParse(r io.Reader, override bool) (Env, error) {
-	env := make(Env)
+// Number of bytes of a line too long to be parsed shown in its ParseError
+const tooLongTextSize = 32
+
+// lineSplitter splits lines like splitLines, cutting the lines longer than max bytes, unless max is not positive.
+// Only the beginning of a cut line is read, the rest of it being skipped without being buffered,
+// unless the splitter stops at the first cut line.
+type lineSplitter struct {
+	max     int
+	stop    bool
+	tooLong bool   // whether the last token is a cut line
+	cut     []byte // beginning of the line being skipped
+}
 
+// bufferSize returns the maximum size of the buffer of the bufio.Scanner,
+// holding a line of max bytes along with its line break.
+func (s *lineSplitter) bufferSize() int {
+	if s.max <= 0 || s.max > math.MaxInt-2 {
+		return math.MaxInt
+	}
+	return s.max + 2
+}
+
+func (s *lineSplitter) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if s.cut == nil {
+		advance, token, err = splitLines(data, atEOF)
+		if token != nil || err != nil || s.max <= 0 || len(data) <= s.max {
+			s.tooLong = false
+			return advance, token, err
+		}
+		s.cut = bytes.Clone(data[:min(len(data), tooLongTextSize)])
+	}
+
+	s.tooLong = true
+	if s.stop {
+		return len(data), s.cut, bufio.ErrFinalToken
+	}
+	idx := bytes.IndexAny(data, "\r\n")
+	switch {
+	case atEOF && idx < 0:
+		token, s.cut = s.cut, nil
+		return len(data), token, bufio.ErrFinalToken
+
+	case idx < 0:
+		return len(data), nil, nil
+	}
+
+	eol := idx + 1
+	if len(data) > eol && data[eol-1] == '\r' && data[eol] == '\n' {
+		eol++
+	}
+	token, s.cut = s.cut, nil
+	return eol, token, nil
+}
+
+// decode returns a reader of the UTF-8 content of r, along with the byte order mark found at its start.
+func decode(r io.Reader) (io.Reader, []byte, error) {
 	buf := new(bytes.Buffer)
 	tee := io.TeeReader(r, buf)
 
@@ -218,192 +289,541 @@ func strictParse(r io.Reader, override bool) (Env, error) {
 	bomByteBuffer := make([]byte, 3)
 	_, err := tee.Read(bomByteBuffer)
 	if err != nil && err != io.EOF {
-		return env, err
+		return nil, nil, err
 	}
 
 	z := io.MultiReader(buf, r)
 
-	// We chooes a different scanner depending on file encoding.
-	var scanner *bufio.Scanner
+	// We chooes a different decoder depending on file encoding.
+	switch {
+	case bytes.HasPrefix(bomByteBuffer, bomUTF8):
+		return transform.NewReader(z, unicode.UTF8BOM.NewDecoder()), bomUTF8, nil
+	case bytes.HasPrefix(bomByteBuffer, bomUTF16LE):
+		return transform.NewReader(z, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()), bomUTF16LE, nil
+	case bytes.HasPrefix(bomByteBuffer, bomUTF16BE):
+		return transform.NewReader(z, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()), bomUTF16BE, nil
+	}
+
+	return z, nil, nil
+}
+
+// parse parses r and returns the first invalid line as an error.
+// When the parser isn't strict, invalid lines are skipped and every one of them is returned, joined with errors.Join.
+func (p *Parser) parse(r io.Reader) (*OrderedEnv, error) {
+	env := NewOrderedEnv()
 
-	if bytes.HasPrefix(bomByteBuffer, bomUTF8) {
-		scanner = bufio.NewScanner(transform.NewReader(z, unicode.UTF8BOM.NewDecoder()))
-	} else if bytes.HasPrefix(bomByteBuffer, bomUTF16LE) {
-		scanner = bufio.NewScanner(transform.NewReader(z, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()))
-	} else if bytes.HasPrefix(bomByteBuffer, bomUTF16BE) {
-		scanner = bufio.NewScanner(transform.NewReader(z, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()))
-	} else {
-		scanner = bufio.NewScanner(z)
+	z, _, err := decode(r)
+	if err != nil {
+		return env, err
 	}
 
-	scanner.Split(splitLines)
+	split := &lineSplitter{max: p.maxSize, stop: p.strict}
+	scanner := bufio.NewScanner(z)
+	scanner.Buffer(nil, split.bufferSize())
+	scanner.Split(split.split)
+
+	var errs []error
+	// with forward references, the entries to resolve and the variables they declare
+	var entries []*entry
+	declared := NewOrderedEnv()
+	// lines to be scanned again after an unterminated quote
+	var pending []string
+	// the openings of the values left unterminated, see lexer.opening
+	unterminated := make(map[string]bool)
+	// next returns the next line, reporting whether it's cut for being too long
+	next := func() (string, bool, bool) {
+		if len(pending) > 0 {
+			l := pending[0]
+			pending = pending[1:]
+			return l, false, true
+		}
+		if !scanner.Scan() {
+			return "", false, false
+		}
+		return scanner.Text(), split.tooLong, true
+	}
+	tooLong := func(line int, text string) *ParseError {
+		return &ParseError{Line: line, Column: 1, Text: text + "...", Err: ErrLineTooLong}
+	}
 
-	for scanner.Scan() {
-		if err := scanner.Err(); err != nil {
-			return env, err
+	lx := p.newLexer()
+	lineNo := 0
+	for {
+		raw, long, ok := next()
+		if !ok {
+			break
 		}
+		lineNo++
+		start := lineNo
 
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || line[0] == '#' {
+		if long {
+			errs = append(errs, tooLong(start, raw))
+			if p.strict {
+				break
+			}
 			continue
 		}
 
-		quote := ""
-		// look for the delimiter character
-		idx := strings.Index(line, "=")
-		if idx == -1 {
-			idx = strings.Index(line, ":")
-		}
-		// look for a quote character
-		if idx > 0 && idx < len(line)-1 {
-			val := strings.TrimSpace(line[idx+1:])
-			if val[0] == '"' || val[0] == '\'' {
-				quote = val[:1]
-				// look for the closing quote character within the same line
-				idx = strings.LastIndex(strings.TrimSpace(val[1:]), quote)
-				if idx >= 0 && val[idx] != '\\' {
-					quote = ""
-				}
-			}
+		if line := strings.TrimSpace(raw); line == "" || line[0] == '#' {
+			continue
 		}
+
+		lx.lex(raw)
+		// The lines following an unterminated value can't close a value opened the same way after it,
+		// so they aren't scanned again.
+		known := lx.open && unterminated[lx.opening()]
 		// look for the closing quote character
-		for quote != "" && scanner.Scan() {
-			l := scanner.Text()
-			line += "\n" + l
-			idx := strings.LastIndex(l, quote)
-			if idx > 0 && l[idx-1] == '\\' {
-				// foud a matching quote character but it's escaped
-				continue
+		var consumed []string
+		valueTooLong := false
+		for lx.open && !known {
+			l, long, ok := next()
+			if !ok {
+				lx.end()
+				break
 			}
-			if idx >= 0 {
-				// foud a matching quote
-				quote = ""
+			lineNo++
+			if long {
+				// the value is too long, whether it's terminated or not
+				valueTooLong = true
+				break
 			}
+			consumed = append(consumed, l)
+			lx.feed("\n" + l)
 		}
 
-		if quote != "" {
-			return env, fmt.Errorf("missing quotes")
+		if valueTooLong || p.maxSize > 0 && len(lx.text) > p.maxSize {
+			text := strings.TrimLeftFunc(lx.text[:min(len(lx.text), tooLongTextSize)], isSpace)
+			errs = append(errs, tooLong(start, text))
+			if p.strict {
+				break
+			}
+			continue
+		}
+
+		if lx.open {
+			errs = append(errs, &ParseError{Line: start, Column: lx.line.valuePos + 1, Text: strings.TrimSpace(raw), Err: lx.missing()})
+			if p.strict {
+				break
+			}
+			if !known {
+				unterminated[lx.opening()] = true
+				// resume right after the line holding the opening quote
+				pending = consumed
+				lineNo = start
+			}
+			continue
 		}
 
-		err := parseLine(line, env, override)
+		t, err := lx.result()
+		var warnings []*ParseError
+		switch {
+		case err != nil:
+		case p.forwardRefs:
+			// variables are expanded once every line is parsed
+			var e *entry
+			e, warnings, err = p.parseEntry(t, start, declared)
+			if e != nil {
+				entries = append(entries, e)
+				declared.Set(e.key, "")
+			}
+		default:
+			warnings, err = p.parseLine(t, start, env)
+		}
+		if p.warn != nil {
+			for _, w := range warnings {
+				p.warn(w)
+			}
+		}
 		if err != nil {
-			return env, err
+			var pe *ParseError
+			if errors.As(err, &pe) && pe.Line == 0 {
+				pe.Line = start
+			}
+			errs = append(errs, err)
+			if p.strict {
+				break
+			}
 		}
 	}
 
-	return env, scanner.Err()
-}
+	if err := scanner.Err(); err != nil && (!p.strict || len(errs) == 0) {
+		errs = append(errs, err)
+	}
 
-var (
-	lineRgx     = regexp.MustCompile(linePattern)
-	unescapeRgx = regexp.MustCompile(`\\([^$])`)
-	varRgx      = regexp.MustCompile(variablePattern)
-)
+	if p.forwardRefs {
+		errs = append(errs, p.resolve(entries, env)...)
+		sortByLine(errs)
+	}
 
-func parseLine(s string, env Env, override bool) error {
-	rm := lineRgx.FindStringSubmatch(s)
+	if p.strict && len(errs) > 0 {
+		return env, errs[0]
+	}
+	return env, errors.Join(errs...)
+}
 
-	if len(rm) == 0 {
-		return checkFormat(s, env)
+// parseLine parses the lexed line t, found at the line number n, into env. Besides the error of an invalid line,
+// it returns the problems that don't prevent the line from being parsed.
+func (p *Parser) parseLine(t *lexedLine, n int, env *OrderedEnv) ([]*ParseError, error) {
+	e, warnings, err := p.parseEntry(t, n, env)
+	if e == nil {
+		return warnings, err
 	}
 
-	key := strings.TrimSpace(rm[1])
-	val := strings.TrimSpace(rm[2])
+	val, expWarnings, err := p.expandEntry(e, env)
+	warnings = append(warnings, expWarnings...)
+	if err != nil {
+		return warnings, err
+	}
 
-	var hsq, hdq bool
+	env.Set(e.key, val)
+	return warnings, nil
+}
 
-	// check if the value is quoted
-	if l := len(val); l >= 2 {
-		l -= 1
-		// has double quotes
-		hdq = val[0] == '"' && val[l] == '"'
-		// has single quotes
-		hsq = val[0] == '\'' && val[l] == '\''
+// entry is a variable declared by a line, before its value is expanded.
+type entry struct {
+	key    string
+	val    string      // the unquoted value
+	parts  []valuePart // the unquoted value split for the expansion, nil when it's not expanded
+	text   string      // the trimmed line
+	line   int
+	indent int
+}
 
-		// remove quotes '' or ""
-		if hsq || hdq {
-			val = val[1:l]
-		}
+// position returns the line number and the column of the offset i of the trimmed line of e,
+// which may span several lines.
+func (e *entry) position(i int) (int, int) {
+	line, col := position(e.text, i)
+	if line == 0 {
+		col += e.indent
 	}
+	return e.line + line, col
+}
 
-	if hdq {
-		val = strings.ReplaceAll(val, `\n`, "\n")
-		val = strings.ReplaceAll(val, `\r`, "\r")
+// parseEntry returns the variable declared by the lexed line t, found at the line number n.
+// The entry is nil for `export KEY` lines, along with an error when the variable is not set in env.
+// Besides the error of an invalid line, it returns the problems that don't prevent the line from being parsed.
+func (p *Parser) parseEntry(t *lexedLine, n int, env *OrderedEnv) (*entry, []*ParseError, error) {
+	e := &entry{key: t.key(), text: strings.TrimSpace(t.text), line: n, indent: t.start}
+	if !t.entry {
+		if _, ok := env.Get(e.key); !ok {
+			return nil, nil, &ParseError{Line: n, Column: t.keyPos + 1, Text: e.text, Err: ErrUnsetExport}
+		}
+		return nil, nil, nil
+	}
+	if p.words {
+		p.parseWord(e, t.value(), p.expansion && !isRaw(t.comment()))
+		return e, nil, nil
+	}
 
-		// Unescape all characters except $ so variables can be escaped properly
-		val = unescapeRgx.ReplaceAllString(val, "$1")
+	var val string
+	var quote byte
+	switch {
+	case t.heredoc && t.quote != 0:
+		// a quoted delimiter disables the expansion, like single quotes
+		val, quote = t.body(), '\''
+	case t.heredoc:
+		val = t.body()
+	default:
+		val, quote = unquote(t.value(), p.quotes)
+		if quote == 0 && p.continuation {
+			val = joinLines(val)
+		}
 	}
+	expand := quote != '\'' && p.expansion && strings.IndexByte(val, '$') >= 0 && !isRaw(t.comment())
 
-	if !hsq {
-		fv := func(s string) string {
-			return varReplacement(s, hsq, env, override)
+	// escape sequences of the value
+	esc := escapesNone
+	switch {
+	case quote == '"':
+		esc = p.escapes
+	case quote == '\'' && p.singleEscapes:
+		esc = escapesSingle
+	case quote == 0 && !t.heredoc && p.shellEscapes:
+		esc = escapesAll
+	}
+	unknown := -1
+	switch {
+	case esc == escapesLegacy:
+		val = legacyUnescape(val)
+	case expand:
+		e.parts, unknown = p.lexValueParts(val, esc)
+	case esc != escapesNone:
+		val, unknown = esc.unescape(val, false)
+	}
+	if expand && e.parts == nil {
+		e.parts, _ = p.lexValueParts(val, escapesNone)
+	}
+	e.val = val
+
+	var warnings []*ParseError
+	if unknown >= 0 && esc == escapesFull {
+		line, col := e.position(t.valuePos - t.start + 1 + unknown)
+		pe := &ParseError{Line: line, Column: col, Text: e.text, Err: ErrUnknownEscape}
+		if p.strict {
+			return nil, nil, pe
 		}
-		val = varRgx.ReplaceAllStringFunc(val, fv)
+		warnings = append(warnings, pe)
 	}
 
-	env[key] = val
-	return nil
+	return e, warnings, nil
 }
 
-func parseExport(st string, env Env) error {
-	if strings.HasPrefix(st, "export") {
-		vs := strings.SplitN(st, " ", 2)
+// parseWord sets the value of e to the word s, removing the quotes of its parts and decoding their escape sequences.
+// The variables of its double quoted and unquoted parts are expanded when expand is true.
+func (p *Parser) parseWord(e *entry, s string, expand bool) {
+	var parts []valuePart
+	for i := 0; i < len(s); {
+		var part string
+		esc := escapesNone
+		switch s[i] {
+		case '\'':
+			end := i + 1 + strings.IndexByte(s[i+1:], '\'')
+			parts = append(parts, valuePart{text: s[i+1 : end]})
+			i = end + 1
+			continue
+		case '"':
+			end := wordPartEnd(s, i+1, `"`)
+			part, esc = s[i+1:end], p.escapes
+			if esc == escapesLegacy {
+				part, esc = legacyUnescape(part), escapesNone
+			}
+			i = end + 1
+		default:
+			end := wordPartEnd(s, i, p.quotes)
+			part = s[i:end]
+			if p.continuation {
+				part = joinLines(part)
+			}
+			if p.shellEscapes {
+				esc = escapesAll
+			}
+			i = end
+		}
+
+		if expand {
+			ps, _ := p.lexValueParts(part, esc)
+			parts = append(parts, ps...)
+		} else {
+			text, _ := esc.unescape(part, false)
+			parts = append(parts, valuePart{text: text})
+		}
+	}
 
-		if len(vs) > 1 {
-			if _, ok := env[vs[1]]; !ok {
-				return fmt.Errorf("line `%s` has an unset variable", st)
+	if expand {
+		e.parts = parts
+		return
+	}
+	var sb strings.Builder
+	for _, part := range parts {
+		sb.WriteString(part.text)
+	}
+	e.val = sb.String()
+}
+
+// wordPartEnd returns the index of the first quote of s found from the index i, which ends a part of a word,
+// skipping the escaped characters and the parameter expansions. It returns the length of s when there is none.
+func wordPartEnd(s string, i int, quotes string) int {
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case strings.IndexByte(quotes, s[i]) >= 0:
+			return i
+		case quotes != `"` && strings.HasPrefix(s[i:], "${"):
+			if end := closingBrace(s, i+2); end >= 0 {
+				i = end
 			}
 		}
 	}
+	return len(s)
+}
+
+// expandEntry returns the value of e with the variables of env expanded.
+// Besides the error preventing the expansion, it returns the problems that don't.
+func (p *Parser) expandEntry(e *entry, env *OrderedEnv) (string, []*ParseError, error) {
+	if e.parts == nil {
+		return e.val, nil, nil
+	}
 
-	return nil
+	val, err := p.expand(e.parts, env)
+	var warnings []*ParseError
+	for _, err := range unjoin(err) {
+		line, col := e.position(refColumn(e.text, err) - 1)
+		pe := &ParseError{Line: line, Column: col, Text: e.text, Err: err}
+		if p.strictExpansion || !errors.Is(err, ErrUndefinedVar) {
+			return "", warnings, pe
+		}
+		warnings = append(warnings, pe)
+	}
+
+	return val, warnings, nil
 }
 
-var varNameRgx = regexp.MustCompile(`(\$)(\{?([A-Z0-9_]+)\}?)`)
+// Directive disabling the variable expansion of a line when found in its comment
+const rawDirective = "gotenv:raw"
+
+// isRaw reports whether the comment holds the raw directive, like `# gotenv:raw` or `# gotenv:raw vendor secret`.
+func isRaw(comment string) bool {
+	fields := strings.Fields(strings.TrimPrefix(comment, "#"))
+	return len(fields) > 0 && fields[0] == rawDirective
+}
+
+// expand concatenates the parts of a value, replacing the variables they refer to.
+// It returns the problems found with the variables, joined with errors.Join.
+func (p *Parser) expand(parts []valuePart, env *OrderedEnv) (string, error) {
+	if len(parts) == 1 && parts[0].name == "" {
+		return parts[0].text, nil
+	}
 
-func varReplacement(s string, hsq bool, env Env, override bool) string {
-	if s == "" {
-		return s
+	var errs []error
+	var sb strings.Builder
+	for _, part := range parts {
+		if part.name == "" {
+			sb.WriteString(part.text)
+			continue
+		}
+		replace, err := p.varReplacement(part, env)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		sb.WriteString(replace)
+	}
+
+	return sb.String(), errors.Join(errs...)
+}
+
+// refColumn returns the 1-based column of the variable reference causing err within the line s.
+func refColumn(s string, err error) int {
+	var ve *varError
+	if errors.As(err, &ve) {
+		for _, ref := range []string{"${" + ve.name + "}", "$" + ve.name, "${" + ve.name} {
+			if idx := strings.Index(s, ref); idx >= 0 {
+				return idx + 1
+			}
+		}
 	}
 
-	if s[0] == '\\' {
-		// the dollar sign is escaped
-		return s[1:]
+	return strings.Index(s, "$") + 1
+}
+
+// unquote removes the quotes around val, which is one of quotes.
+// It returns the quote character found, or 0 for an unquoted value.
+func unquote(val, quotes string) (string, byte) {
+	l := len(val) - 1
+	if l < 1 {
+		return val, 0
 	}
 
-	if hsq {
-		return s
+	if strings.IndexByte(quotes, val[0]) >= 0 && val[l] == val[0] {
+		return val[1:l], val[0]
 	}
 
-	mn := varNameRgx.FindStringSubmatch(s)
+	return val, 0
+}
 
-	if len(mn) == 0 {
-		return s
+// joinLines joins the lines of an unquoted value continued by a trailing backslash.
+// The backslash ending the last line of the input is dropped as well.
+func joinLines(val string) string {
+	val = strings.ReplaceAll(val, "\\\n", "")
+	if continued(val) {
+		val = val[:len(val)-1]
 	}
+	return val
+}
 
-	v := mn[3]
+// legacyUnescape unescapes a double quoted value the way of gotenv 1.6: \n and \r are converted
+// and the backslash is removed in front of every other character except $ so variables can be escaped properly.
+func legacyUnescape(val string) string {
+	val = strings.ReplaceAll(val, `\n`, "\n")
+	val = strings.ReplaceAll(val, `\r`, "\r")
 
-	if replace, ok := os.LookupEnv(v); ok && !override {
-		return replace
+	idx := strings.IndexByte(val, '\\')
+	if idx < 0 {
+		return val
 	}
 
-	if replace, ok := env[v]; ok {
-		return replace
+	var sb strings.Builder
+	sb.Grow(len(val))
+	sb.WriteString(val[:idx])
+	for i := idx; i < len(val); i++ {
+		if val[i] == '\\' && i+1 < len(val) && val[i+1] != '$' {
+			i++
+		}
+		sb.WriteByte(val[i])
 	}
+	return sb.String()
+}
 
-	return os.Getenv(v)
+// varReplacement returns the value of the variable referenced by part.
+func (p *Parser) varReplacement(part valuePart, env *OrderedEnv) (string, error) {
+	if part.op != "" {
+		return p.paramExpansion(part.name, part.op, part.word, env)
+	}
+
+	replace, ok := p.lookupVar(part.name, env)
+	if !ok && (p.strictExpansion || p.warn != nil) {
+		return "", &varError{name: part.name, err: ErrUndefinedVar}
+	}
+	return replace, nil
 }
 
-func checkFormat(s string, env Env) error {
-	st := strings.TrimSpace(s)
+// lookupVar retrieves the value of a variable from the file and the lookup function.
+// Values of the lookup function win unless overriding.
+func (p *Parser) lookupVar(v string, env *OrderedEnv) (string, bool) {
+	if p.lookup == nil {
+		return env.Get(v)
+	}
 
-	if st == "" || st[0] == '#' {
-		return nil
+	if replace, ok := p.lookup(v); ok && !p.override {
+		return replace, true
 	}
 
-	if err := parseExport(st, env); err != nil {
-		return err
+	if replace, ok := env.Get(v); ok {
+		return replace, true
 	}
 
-	return fmt.Errorf("line `%s` doesn't match format", s)
+	return p.lookup(v)
+}
+
+// paramExpansion expands ${v<op>word} following the shell rules.
+// With a colon, the operators treat an empty variable like an unset one.
+func (p *Parser) paramExpansion(v, op string, word []valuePart, env *OrderedEnv) (string, error) {
+	val, ok := p.lookupVar(v, env)
+	set := ok && (val != "" || op[0] != ':')
+
+	switch op[len(op)-1] {
+	case '-':
+		// use a default value
+		if set {
+			return val, nil
+		}
+		return p.expand(word, env)
+	case '=':
+		// assign a default value
+		if set {
+			return val, nil
+		}
+		word, err := p.expand(word, env)
+		env.Set(v, word)
+		return word, err
+	case '?':
+		// fail when unset
+		if set {
+			return val, nil
+		}
+		word, err := p.expand(word, env)
+		if err != nil {
+			return "", err
+		}
+		if word == "" {
+			word = "parameter null or not set"
+		}
+		return "", &varError{name: v, msg: word, err: ErrRequiredVar}
+	default:
+		// use an alternate value
+		if !set {
+			return "", nil
+		}
+		return p.expand(word, env)
+	}
 }
@@ -2,11 +2,14 @@ package gotenv_test
 
 import (
 	"bufio"
+	"embed"
 	"errors"
 	"io"
+	"io/fs"
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/subosito/gotenv"
@@ -119,7 +122,8 @@ var formats = []struct {
 	{`FOO="bar\rbaz"`, gotenv.Env{"FOO": "bar\rbaz"}, false},
 
 	// escape $ properly when no alphabets/numbers/_  are followed by it
-	{`FOO="bar\\$ \\$\\$"`, gotenv.Env{"FOO": "bar$ $$"}, false},
+	{`FOO="bar\$ \$\$"`, gotenv.Env{"FOO": "bar$ $$"}, false},
+	{`FOO=bar\$ \$\$`, gotenv.Env{"FOO": "bar$ $$"}, false},
 
 	// ignore $ when it is not escaped and no variable is followed by it
 	{`FOO="bar $ "`, gotenv.Env{"FOO": "bar $ "}, false},
@@ -132,6 +136,131 @@ var formats = []struct {
 
 	// allows = in double quoted values with newlines (typically base64 padding)
 	{`foo="---\na==\n---"`, gotenv.Env{"foo": "---\na==\n---"}, false},
+
+	// expands lowercase, mixed case and dotted variable names like the keys
+	{"db_host=x\nurl=$db_host", gotenv.Env{"db_host": "x", "url": "x"}, false},
+	{"dbHost=x\nurl=\"${dbHost}:5432\"", gotenv.Env{"dbHost": "x", "url": "x:5432"}, false},
+	{"db.host=x\nurl=${db.host}", gotenv.Env{"db.host": "x", "url": "x"}, false},
+	{"db.host=x\nport=${db.port:-5432}", gotenv.Env{"db.host": "x", "port": "5432"}, false},
+	{"db=x\nurl=$db.host", gotenv.Env{"db": "x", "url": "x.host"}, false},
+	{"url='$db_host'", gotenv.Env{"url": "$db_host"}, false},
+
+	// does not expand variables of lines with the raw directive
+	{`PASS=pa$$w0rd$ # gotenv:raw`, gotenv.Env{"PASS": "pa$$w0rd$"}, false},
+	{`PASS="p@$word" #gotenv:raw vendor secret`, gotenv.Env{"PASS": "p@$word"}, false},
+	{`BAR="$FOO\$FOO" # gotenv:raw`, gotenv.Env{"BAR": `$FOO\$FOO`}, true},
+	{"CERT=\"a\n$b\" # gotenv:raw", gotenv.Env{"CERT": "a\n$b"}, false},
+	{`PASS=p@$word # gotenv:rawr`, gotenv.Env{"PASS": "p@"}, false},
+	{`PASS=p@$word # not gotenv:raw`, gotenv.Env{"PASS": "p@"}, false},
+
+	// expands default values
+	{`PORT=${PORT:-8080}`, gotenv.Env{"PORT": "8080"}, false},
+	{"EMPTY=\nA=${EMPTY:-default}\nB=${EMPTY-default}", gotenv.Env{"EMPTY": "", "A": "default", "B": ""}, false},
+	{`HOST="${HOST-localhost}:${PORT:-80}"`, gotenv.Env{"HOST": "localhost:80"}, false},
+	{`BAR=${FOO:-default}`, gotenv.Env{"BAR": "test"}, true},
+	{"B=b\nA=${NOPE:-${B}/c}", gotenv.Env{"B": "b", "A": "b/c"}, false},
+
+	// assigns default values
+	{"A=${B:=x}\nC=$B", gotenv.Env{"A": "x", "B": "x", "C": "x"}, false},
+	{"B=\nA=${B=x}", gotenv.Env{"A": "", "B": ""}, false},
+
+	// expands alternate values
+	{"DEBUG=1\nFLAGS=${DEBUG:+--verbose}", gotenv.Env{"DEBUG": "1", "FLAGS": "--verbose"}, false},
+	{"DEBUG=\nA=${DEBUG:+--verbose}\nB=${DEBUG+--verbose}", gotenv.Env{"DEBUG": "", "A": "", "B": "--verbose"}, false},
+	{`FLAGS=${DEBUG:+--verbose}`, gotenv.Env{"FLAGS": ""}, false},
+
+	// expands required values when set
+	{`BAR=${FOO:?FOO must be set}`, gotenv.Env{"BAR": "test"}, true},
+
+	// does not expand parameter expansions in single quotes or when escaped
+	{`FOO='${BAR:-baz}'`, gotenv.Env{"FOO": "${BAR:-baz}"}, false},
+	{`FOO="\${BAR:-baz}"`, gotenv.Env{"FOO": "${BAR:-baz}"}, false},
+	{`FOO="\${BAR:-$BAZ}"`, gotenv.Env{"FOO": "${BAR:-$BAZ}"}, false},
+
+	// expands nested parameter expansions
+	{"C=c\nA=${NOPE:-${NEVER:-${C}}}", gotenv.Env{"C": "c", "A": "c"}, false},
+	{`A=${NOPE:-{x}}`, gotenv.Env{"A": "{x}"}, false},
+
+	// keeps dollar signs which are not followed by a variable
+	{`A=${NOPE`, gotenv.Env{"A": "${NOPE"}, false},
+	{`A=$NOPE}`, gotenv.Env{"A": "}"}, false},
+
+	// closes quoted values at the first quote followed by a comment at most
+	{`FOO="bar" # it's "baz"`, gotenv.Env{"FOO": "bar"}, false},
+	{`FOO='it's'`, gotenv.Env{"FOO": "it's"}, false},
+
+	// reads values unquoted when a quote followed by text doesn't close them on their line
+	{"A='a'b\nB=1\nC='x'", gotenv.Env{"A": "'a'b", "B": "1", "C": "x"}, false},
+	{`A="x"y`, gotenv.Env{"A": `"x"y`}, false},
+	{`A="x"y # comment`, gotenv.Env{"A": `"x"y`}, false},
+
+	// reads escaped backslashes before the closing quote
+	{`FOO="bar\\"`, gotenv.Env{"FOO": `bar\`}, false},
+	{"FOO=\"bar\\\\\"\nBAZ=1", gotenv.Env{"FOO": `bar\`, "BAZ": "1"}, false},
+
+	// reads backslashes literally in single quoted values
+	{`FOO='bar\'`, gotenv.Env{"FOO": `bar\`}, false},
+
+	// decodes escape sequences in double quoted values
+	{`FOO="a\tb\\c\"d"`, gotenv.Env{"FOO": "a\tb\\c\"d"}, false},
+	{`FOO="\a\b\f\v\0"`, gotenv.Env{"FOO": "\a\b\f\v\x00"}, false},
+	{`FOO="\x41\u00e9\U0001F600"`, gotenv.Env{"FOO": "A\u00e9\U0001F600"}, false},
+	{`BAR="\\$FOO\t\$FOO"`, gotenv.Env{"BAR": "\\test\t$FOO"}, true},
+	{`BAR="${NOPE:-\"$FOO\"}"`, gotenv.Env{"BAR": `"test"`}, true},
+	{`FOO=a\tb`, gotenv.Env{"FOO": `a\tb`}, false},
+	{`FOO='a\tb'`, gotenv.Env{"FOO": `a\tb`}, false},
+
+	// joins the lines of values continued by a backslash
+	{"JAVA_OPTS=-Xmx2g \\\n-Dfoo=bar\nB=1", gotenv.Env{"JAVA_OPTS": "-Xmx2g -Dfoo=bar", "B": "1"}, false},
+	{"A=a\\\r\nb \\\r\n\r\nB=1", gotenv.Env{"A": "ab ", "B": "1"}, false},
+	{"A=a \\\nb # c\nB=1", gotenv.Env{"A": "a b", "B": "1"}, false},
+	{"A=a\\", gotenv.Env{"A": "a"}, false},
+	{`A=C:\\`, gotenv.Env{"A": `C:\\`}, false},
+	{"A=$FOO\\\n$FOO", gotenv.Env{"A": "testtest"}, true},
+	{"A=\"a\\\nb\"", gotenv.Env{"A": "ab"}, false},
+	{"A='a\\\nb'", gotenv.Env{"A": "a\\\nb"}, false},
+
+	// reads backtick quoted values
+	{"foo=`bar`", gotenv.Env{"foo": "bar"}, false},
+	{"foo=`it's \"quoted\"`", gotenv.Env{"foo": `it's "quoted"`}, false},
+	{"foo=`ba#r` # comment", gotenv.Env{"foo": "ba#r"}, false},
+	{"foo=`a\\nb\\`", gotenv.Env{"foo": `a\nb\`}, false},
+	{"foo=`a\nb`\nbar=1", gotenv.Env{"foo": "a\nb", "bar": "1"}, false},
+	{"foo=`a` b`", gotenv.Env{"foo": "a` b"}, false},
+	{"BAR=`$FOO \\$FOO`", gotenv.Env{"BAR": "test $FOO"}, true},
+
+	// reads heredoc values
+	{"A=<<EOF\na\n\"b\" 'c'\nEOF\nB=1", gotenv.Env{"A": "a\n\"b\" 'c'", "B": "1"}, false},
+	{"A=<<EOF # comment\r\na\r\n\r\n EOF \r\nB=1", gotenv.Env{"A": "a\n", "B": "1"}, false},
+	{"A=<<EOF\nEOF", gotenv.Env{"A": ""}, false},
+	{"A=<<EOF\n$FOO \\$FOO\nEOF", gotenv.Env{"A": "test $FOO"}, true},
+	{"A=<<'EOF'\n$FOO \\$FOO\nEOF", gotenv.Env{"A": "$FOO \\$FOO"}, true},
+	{"A=<<\"EOF\"\n$FOO\nEOF", gotenv.Env{"A": "$FOO"}, true},
+	{"A=<<EOF # gotenv:raw\n$FOO\nEOF", gotenv.Env{"A": "$FOO"}, true},
+	{"A=<<EOF\nEOF2\nEOF", gotenv.Env{"A": "EOF2"}, false},
+	{"A=<<EOF x", gotenv.Env{"A": "<<EOF x"}, false},
+	{"A=<<", gotenv.Env{"A": "<<"}, false},
+
+	// keeps unknown escape sequences
+	{`FOO="a\qb\x4"`, gotenv.Env{"FOO": `a\qb\x4`}, false},
+}
+
+func TestParse_legacyEscapes(t *testing.T) {
+	tests := []struct {
+		in  string
+		out gotenv.Env
+	}{
+		// escape $ properly when no alphabets/numbers/_  are followed by it
+		{`FOO="bar\\$ \\$\\$"`, gotenv.Env{"FOO": "bar$ $$"}},
+		{`FOO="bar\tbaz\nqux"`, gotenv.Env{"FOO": "bartbaz\nqux"}},
+	}
+
+	p := gotenv.NewParser(gotenv.WithLegacyEscapes(true))
+	for _, tt := range tests {
+		env, err := p.Parse(strings.NewReader(tt.in))
+		assert.Nil(t, err, tt.in)
+		assert.Equal(t, tt.out, env, tt.in)
+	}
 }
 
 var errorFormats = []struct {
@@ -140,10 +269,33 @@ var errorFormats = []struct {
 	err string
 }{
 	// allows export line if you want to do it that way and checks for unset variables
-	{"OPTION_A=2\nexport OH_NO_NOT_SET", gotenv.Env{"OPTION_A": "2"}, "line `export OH_NO_NOT_SET` has an unset variable"},
+	{"OPTION_A=2\nexport OH_NO_NOT_SET", gotenv.Env{"OPTION_A": "2"}, "2:8: line `export OH_NO_NOT_SET` has an unset variable"},
+
+	// throws an error if a required variable is not set
+	{"A=1\nTOKEN=${TOKEN:?TOKEN must be set}", gotenv.Env{"A": "1"}, "2:7: line `TOKEN=${TOKEN:?TOKEN must be set}` has an unset required variable: TOKEN: TOKEN must be set"},
+	{"EMPTY=\nA=${EMPTY:?}", gotenv.Env{"EMPTY": ""}, "2:3: line `A=${EMPTY:?}` has an unset required variable: EMPTY: parameter null or not set"},
 
 	// throws an error if line format is incorrect
-	{`lol$wut`, gotenv.Env{}, "line `lol$wut` doesn't match format"},
+	{`lol$wut`, gotenv.Env{}, "1:4: line `lol$wut` doesn't match format"},
+
+	// reports the position of the first invalid line
+	{"FOO=bar\n\n  BAR baz", gotenv.Env{"FOO": "bar"}, "3:7: line `BAR baz` doesn't match format"},
+
+	// throws an error if a double quoted value holds an unknown escape sequence
+	{"A=1\nB=\"a\\qb\"", gotenv.Env{"A": "1"}, "2:5: line `B=\"a\\qb\"` has an unknown escape sequence"},
+	{"A=\"a\nb\\uD800\"", gotenv.Env{}, "2:2: line `A=\"a\nb\\uD800\"` has an unknown escape sequence"},
+
+	// reports the line of the error within a continued value
+	{"A=1\nB=x \\\n  ${NOPE:?}", gotenv.Env{"A": "1"}, "3:3: line `B=x \\\n  ${NOPE:?}` has an unset required variable: NOPE: parameter null or not set"},
+
+	// throws an error if a heredoc value is never closed
+	{"A=1\nB=<<EOF\nb\nC=3", gotenv.Env{"A": "1"}, "2:3: line `B=<<EOF` has a missing heredoc delimiter"},
+
+	// throws an error if a backtick quoted value is never closed
+	{"FOO=1\nBAR=`bar\nBAZ=1", gotenv.Env{"FOO": "1"}, "2:5: line `BAR=`bar` has missing quotes"},
+
+	// throws an error if a quoted value is never closed
+	{"FOO=bar\nBAR= \"baz\nqux", gotenv.Env{"FOO": "bar"}, "2:6: line `BAR= \"baz` has missing quotes"},
 }
 
 var fixtures = []struct {
@@ -211,6 +363,44 @@ and 1 variable
 `,
 		},
 	},
+	{
+		"fixtures/backtick.env",
+		gotenv.Env{
+			"OPTION_A": "1",
+			"OPTION_B": "2",
+			"OPTION_C": "",
+			"OPTION_D": `\n`,
+			"OPTION_E": `it's "quoted"`,
+			"OPTION_F": "ba#r",
+			"OPTION_G": "\n",
+			"OPTION_H": `some multi-line text
+with 'single' and "double" quotes and 1 variable`,
+			"OPTION_I": `some!*chars=qweq""e$$$""`,
+			"OPTION_J": `some multi-line text
+with 'quotes' # and a hash
+empty lines
+
+and 1 variable
+`,
+		},
+	},
+	{
+		"fixtures/heredoc.env",
+		gotenv.Env{
+			"APP_HOST": "localhost",
+			"CERT": `-----BEGIN CERTIFICATE-----
+MIIBszCCAVmgAwIBAgIUb3Vy+dGVzdC1jZXJ0aWZpY2F0ZQ==
+-----END CERTIFICATE-----`,
+			"CONFIG": `{
+  "url": "https://$APP_HOST/api",
+  "quote": "it's \"quoted\""
+}`,
+			"GREETING": `Hello from localhost, $APP_HOST
+
+  # not a comment`,
+			"EMPTY": "",
+		},
+	},
 	{
 		"fixtures/yaml.env",
 		gotenv.Env{
@@ -242,6 +432,91 @@ func TestStrictParse(t *testing.T) {
 	}
 }
 
+func TestApply_requiredVariable(t *testing.T) {
+	defer os.Clearenv()
+
+	os.Setenv("TOKEN", "s3cret")
+	err := gotenv.OverApply(strings.NewReader("AUTH=Bearer ${TOKEN:?missing token}"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer s3cret", os.Getenv("AUTH"))
+
+	os.Unsetenv("TOKEN")
+	err = gotenv.Apply(strings.NewReader("OTHER=${TOKEN?missing token}"))
+	assert.ErrorIs(t, err, gotenv.ErrRequiredVar)
+	assert.ErrorContains(t, err, "TOKEN: missing token")
+}
+
+func TestStrictParse_ParseError(t *testing.T) {
+	_, err := gotenv.StrictParse(strings.NewReader("FOO=bar\nexport BAR"))
+
+	var pe *gotenv.ParseError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.Equal(t, 2, pe.Line)
+		assert.Equal(t, 8, pe.Column)
+		assert.Equal(t, "export BAR", pe.Text)
+	}
+	assert.ErrorIs(t, err, gotenv.ErrUnsetExport)
+
+	_, err = gotenv.Unmarshal(`FOO="bar`)
+	assert.ErrorIs(t, err, gotenv.ErrMissingQuote)
+}
+
+func TestStrictParseAll(t *testing.T) {
+	in := "FOO=bar\nlol$wut\nBAR=\"unterminated\nBAZ=qux\nexport NOPE\n"
+
+	env, err := gotenv.StrictParseAll(strings.NewReader(in))
+	assert.Equal(t, gotenv.Env{"FOO": "bar", "BAZ": "qux"}, env)
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.ErrorIs(t, err, gotenv.ErrMissingQuote)
+	assert.ErrorIs(t, err, gotenv.ErrUnsetExport)
+	assert.EqualError(t, err, "2:4: line `lol$wut` doesn't match format\n"+
+		"3:5: line `BAR=\"unterminated` has missing quotes\n"+
+		"5:8: line `export NOPE` has an unset variable")
+
+	env, err = gotenv.StrictParseAll(strings.NewReader("FOO=bar"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"FOO": "bar"}, env)
+}
+
+func TestParseWithWarnings(t *testing.T) {
+	env, warnings := gotenv.ParseWithWarnings(strings.NewReader("lol$wut\nFOO=bar\nBAR baz"))
+	assert.Equal(t, gotenv.Env{"FOO": "bar"}, env)
+	if assert.Len(t, warnings, 2) {
+		var pe *gotenv.ParseError
+		assert.ErrorAs(t, warnings[1], &pe)
+		assert.Equal(t, 3, pe.Line)
+	}
+
+	// Parse keeps going after an invalid line
+	assert.Equal(t, env, gotenv.Parse(strings.NewReader("lol$wut\nFOO=bar\nBAR baz")))
+}
+
+func TestParseWithWarnings_expansion(t *testing.T) {
+	env, warnings := gotenv.ParseWithWarnings(strings.NewReader("A=$DATABSE_URL\nlol$wut\nB=\"a\\qb\""))
+	assert.Equal(t, gotenv.Env{"A": "", "B": `a\qb`}, env)
+	if assert.Len(t, warnings, 3) {
+		assert.ErrorIs(t, warnings[0], gotenv.ErrUndefinedVar)
+		assert.ErrorIs(t, warnings[1], gotenv.ErrInvalidLine)
+		assert.ErrorIs(t, warnings[2], gotenv.ErrUnknownEscape)
+		assert.EqualError(t, warnings[0], "1:3: line `A=$DATABSE_URL` has an undefined variable: DATABSE_URL")
+	}
+}
+
+func TestStrictParseAll_unterminatedQuotes(t *testing.T) {
+	// the lines following an unterminated value are scanned again for the other openings only
+	in := "A=\"a\nB='b\nC=\"c\nD=1\nE=e\nF=<<EOF\nG=\"g\nEOF"
+	env, err := gotenv.StrictParseAll(strings.NewReader(in))
+	assert.Equal(t, gotenv.Env{"D": "1", "E": "e", "F": "G=\"g"}, env)
+	assert.EqualError(t, err, "1:3: line `A=\"a` has missing quotes\n"+
+		"2:3: line `B='b` has missing quotes\n"+
+		"3:3: line `C=\"c` has missing quotes")
+
+	// every line is reported once, see BenchmarkStrictParseAll_unterminated for the time it takes
+	in = strings.Repeat("A=\"x\n", 1000)
+	_, warnings := gotenv.ParseWithWarnings(strings.NewReader(in))
+	assert.Len(t, warnings, 1000)
+}
+
 type failingReader struct {
 	io.Reader
 }
@@ -336,6 +611,24 @@ func TestLoad_Env(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestLoad_parseErrorFilename(t *testing.T) {
+	defer os.Clearenv()
+
+	err := gotenv.Load(".env", ".env.invalid")
+
+	var pe *gotenv.ParseError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.Equal(t, ".env.invalid", pe.Filename)
+		assert.Equal(t, 1, pe.Line)
+	}
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.EqualError(t, err, ".env.invalid:1:4: line `lol$wut` doesn't match format")
+
+	_, err = gotenv.Read(".env.invalid")
+	assert.ErrorAs(t, err, &pe)
+	assert.Equal(t, ".env.invalid", pe.Filename)
+}
+
 func TestLoad_nonExist(t *testing.T) {
 	file := ".env.not.exist"
 
@@ -345,6 +638,47 @@ func TestLoad_nonExist(t *testing.T) {
 	}
 }
 
+func TestLoadOptional(t *testing.T) {
+	defer os.Clearenv()
+
+	os.Setenv("B", "fromEnv")
+	skipped, err := gotenv.LoadOptional(".env.not.exist", "fixtures/vars.env", "fixtures/missing.env")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{".env.not.exist", "fixtures/missing.env"}, skipped)
+	assert.Equal(t, "fromEnv", os.Getenv("B"))
+	assert.Equal(t, "fromFile", os.Getenv("A"))
+
+	skipped, err = gotenv.LoadOptional()
+	assert.Nil(t, err)
+	assert.Empty(t, skipped)
+	assert.Equal(t, "world", os.Getenv("HELLO"))
+}
+
+func TestLoadOptional_errors(t *testing.T) {
+	defer os.Clearenv()
+
+	skipped, err := gotenv.LoadOptional(".env.not.exist", ".env.invalid", ".env")
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.EqualError(t, err, ".env.invalid:1:4: line `lol$wut` doesn't match format")
+	assert.Equal(t, []string{".env.not.exist"}, skipped)
+	assert.Equal(t, "", os.Getenv("HELLO"))
+
+	// a directory exists, but it can't be read
+	_, err = gotenv.LoadOptional("fixtures")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestOverLoadOptional(t *testing.T) {
+	defer os.Clearenv()
+
+	os.Setenv("HELLO", "universe")
+	skipped, err := gotenv.OverLoadOptional(".env", ".env.not.exist")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{".env.not.exist"}, skipped)
+	assert.Equal(t, "world", os.Getenv("HELLO"))
+}
+
 func TestLoad_unicodeBOMFixture(t *testing.T) {
 	file := "fixtures/utf8_bom.env"
 
@@ -457,6 +791,105 @@ func TestMustOverLoad_nonExist(t *testing.T) {
 	assert.Panics(t, func() { gotenv.Must(gotenv.OverLoad, ".env.not.exist") }, "Caling gotenv.Must with Overgotenv.Load and non exist file SHOULD panic")
 }
 
+//go:embed fixtures/*.env
+var embedded embed.FS
+
+func TestLoadFS(t *testing.T) {
+	for _, tt := range fixtures {
+		err := gotenv.LoadFS(embedded, tt.filename)
+		assert.Nil(t, err)
+
+		for key, val := range tt.results {
+			assert.Equal(t, val, os.Getenv(key))
+		}
+
+		os.Clearenv()
+	}
+}
+
+func TestLoadFS_default(t *testing.T) {
+	defer os.Clearenv()
+
+	fsys := fstest.MapFS{
+		".env":       {Data: []byte("HELLO=world\nWORLD=$HELLO")},
+		".env.local": {Data: []byte("HELLO=universe\nLOCAL=1")},
+	}
+
+	os.Setenv("WORLD", "preset")
+	err := gotenv.LoadFS(fsys)
+	assert.Nil(t, err)
+	assert.Equal(t, "world", os.Getenv("HELLO"))
+	assert.Equal(t, "preset", os.Getenv("WORLD"))
+
+	// the first value set for a variable wins
+	err = gotenv.LoadFS(fsys, ".env.local")
+	assert.Nil(t, err)
+	assert.Equal(t, "world", os.Getenv("HELLO"))
+	assert.Equal(t, "1", os.Getenv("LOCAL"))
+}
+
+func TestLoadFS_errors(t *testing.T) {
+	defer os.Clearenv()
+
+	fsys := fstest.MapFS{
+		"app/.env":     {Data: []byte("A=1")},
+		"app/bad.env":  {Data: []byte("B=2\nlol$wut")},
+		"app/next.env": {Data: []byte("C=3")},
+	}
+
+	err := gotenv.LoadFS(fsys, "app/.env", "app/bad.env", "app/next.env")
+	assert.EqualError(t, err, "app/bad.env:2:4: line `lol$wut` doesn't match format")
+	assert.Equal(t, "1", os.Getenv("A"))
+	assert.Equal(t, "", os.Getenv("B"))
+	assert.Equal(t, "", os.Getenv("C"))
+
+	err = gotenv.LoadFS(fsys, "app/missing.env")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestOverLoadFS(t *testing.T) {
+	defer os.Clearenv()
+
+	fsys := fstest.MapFS{
+		".env":       {Data: []byte("HELLO=world")},
+		".env.local": {Data: []byte("HELLO=universe")},
+	}
+
+	os.Setenv("HELLO", "preset")
+	err := gotenv.OverLoadFS(fsys)
+	assert.Nil(t, err)
+	assert.Equal(t, "world", os.Getenv("HELLO"))
+
+	// the last value set for a variable wins
+	err = gotenv.OverLoadFS(fsys, ".env", ".env.local")
+	assert.Nil(t, err)
+	assert.Equal(t, "universe", os.Getenv("HELLO"))
+
+	err = gotenv.OverLoadFS(fsys, ".env.missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestReadFS(t *testing.T) {
+	for _, tt := range fixtures {
+		env, err := gotenv.ReadFS(embedded, tt.filename)
+		assert.Nil(t, err)
+
+		for key, val := range tt.results {
+			assert.Equal(t, val, env[key])
+		}
+
+		os.Clearenv()
+	}
+
+	fsys := fstest.MapFS{"bad.env": {Data: []byte("lol$wut")}}
+	_, err := gotenv.ReadFS(fsys, "bad.env")
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.EqualError(t, err, "bad.env:1:4: line `lol$wut` doesn't match format")
+
+	_, err = gotenv.ReadFS(fsys, "missing.env")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
 func TestApply(t *testing.T) {
 	os.Setenv("HELLO", "world")
 	r := strings.NewReader("HELLO=universe")
@@ -495,3 +928,49 @@ QUOTED="some \"quoted\" text"`
 	assert.Nil(t, err)
 	assert.Equal(t, env, out)
 }
+
+func BenchmarkStrictParse(b *testing.B) {
+	var sb strings.Builder
+	for _, tt := range fixtures {
+		data, err := os.ReadFile(tt.filename)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sb.Write(data)
+		sb.WriteString("\n")
+	}
+	for _, tt := range formats {
+		if !tt.preset {
+			sb.WriteString(tt.in + "\n")
+		}
+	}
+
+	benchmarks := []struct {
+		name string
+		data string
+	}{
+		{"fixtures", sb.String()},
+		{"plain", strings.Repeat("# comment\nOPTION_A=1\nexport OPTION_B=some value # inline comment\n", 100)},
+		{"quoted", strings.Repeat("OPTION_A='1'\nOPTION_B=\"some \\\"quoted\\\" value\"\nOPTION_C=\"multi-line\nvalue\"\n", 100)},
+		{"expansion", strings.Repeat("HOST=localhost\nPORT=${PORT:-5432}\nURL=\"postgres://$HOST:${PORT}/db\"\n", 100)},
+		{"long", "CERT=\"" + strings.Repeat("MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA\n", 1000) + "\""},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bm.data)))
+			for range b.N {
+				_, _ = gotenv.StrictParse(strings.NewReader(bm.data))
+			}
+		})
+	}
+}
+
+func BenchmarkStrictParseAll_unterminated(b *testing.B) {
+	data := strings.Repeat("A=\"x\n", 1000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		_, _ = gotenv.StrictParseAll(strings.NewReader(data))
+	}
+}
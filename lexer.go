@@ -0,0 +1,883 @@
+package gotenv
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// lexer splits a line of an env file into its parts, following the grammar of a Parser, which may restrict it:
+//
+//	line      = [ "export" blanks ] key separator [ value ] [ blanks ] [ comment ]
+//	key       = 1*( ALPHA / DIGIT / "_" / "." )
+//	separator = [ blanks ] "=" [ blanks ] / ":" blanks
+//	value     = single-quoted / double-quoted / backtick-quoted / heredoc / unquoted
+//	heredoc   = "<<" delimiter [ blanks ] [ comment ] LF *( *char LF ) delimiter
+//	comment   = "#" *char
+//
+// It's a state machine going through the line once. A value may span several lines: when the closing quote
+// of a quoted value is not found, when an unquoted value ends with a backslash, or until the closing delimiter
+// of a heredoc, the lexer stops at the end of the text and resumes once the next line is fed.
+type lexer struct {
+	syntax
+
+	text  string
+	sb    strings.Builder // holds the text once a line is fed
+	pos   int
+	state stateFn
+	open  bool   // waiting for the closing quote of the value, or for the line continuing it
+	delim string // delimiter of a heredoc value
+	line  lexedLine
+}
+
+// stateFn lexes a part of the line and returns the state lexing the next one, or nil at the end of the line.
+type stateFn func(*lexer) stateFn
+
+// lexedLine is a line split by the lexer. Its parts are offsets within the text.
+type lexedLine struct {
+	text string
+
+	entry      bool // false for `export KEY` lines
+	start      int  // offset of the export keyword or the key
+	keyPos     int
+	keyEnd     int
+	valuePos   int
+	valueEnd   int
+	commentPos int  // -1 without comment
+	quote      byte // quote of the value, or of the delimiter of a heredoc value
+	stray      bool // the value holds a quote like its opening one which is not closing it, like 'a'b
+	errPos     int  // offset where the line stops matching the grammar, -1 for valid lines
+
+	// heredoc values only, the value being the opening delimiter like <<EOF
+	heredoc bool
+	bodyPos int // offset of the line break ending the first line
+	bodyEnd int // offset of the line break preceding the closing delimiter, bodyPos for an empty body
+}
+
+func (t *lexedLine) key() string {
+	return t.text[t.keyPos:t.keyEnd]
+}
+
+// value returns the value as written, quotes included.
+func (t *lexedLine) value() string {
+	return t.text[t.valuePos:t.valueEnd]
+}
+
+// comment returns the comment of the first line.
+func (t *lexedLine) comment() string {
+	if t.commentPos < 0 {
+		return ""
+	}
+	c := t.text[t.commentPos:]
+	if i := strings.IndexAny(c, "\r\n"); i >= 0 {
+		return c[:i]
+	}
+	return c
+}
+
+// body returns the lines of a heredoc value between its delimiters.
+func (t *lexedLine) body() string {
+	if t.bodyEnd == t.bodyPos {
+		return ""
+	}
+	return t.text[t.bodyPos+eolSize(t.text, t.bodyPos) : t.bodyEnd]
+}
+
+// newLexer returns a lexer following the grammar of p. It can lex several lines, one after the other.
+func (p *Parser) newLexer() *lexer {
+	return &lexer{syntax: p.syntax}
+}
+
+// lex lexes the line s, stopping at its end or within a quoted value which isn't closed.
+func (l *lexer) lex(s string) {
+	l.text = s
+	l.sb.Reset()
+	l.pos = 0
+	l.state = lexStart
+	l.open = false
+	l.line = lexedLine{commentPos: -1, errPos: -1}
+	l.run()
+}
+
+// feed adds s, a line break followed by the next line, to the text and resumes the lexing of an open value.
+func (l *lexer) feed(s string) {
+	if l.sb.Len() == 0 {
+		l.sb.WriteString(l.text)
+	}
+	l.sb.WriteString(s)
+	l.text = l.sb.String()
+	l.open = false
+	l.run()
+}
+
+// end ends the lexing at the end of the input. An unquoted value continued on the next line ends with the text,
+// while a quoted or heredoc value stays open.
+func (l *lexer) end() {
+	if l.open && l.line.quote == 0 && !l.line.heredoc {
+		l.open = false
+		l.state = nil
+	}
+}
+
+// opening returns the way the open value was opened: its quote character, or the delimiter of a heredoc.
+// Whether the following lines close the value only depends on it.
+func (l *lexer) opening() string {
+	if l.line.heredoc {
+		return "<<" + l.delim
+	}
+	return string(l.line.quote)
+}
+
+// missing returns the error of a value left open at the end of the input.
+func (l *lexer) missing() error {
+	if l.line.heredoc {
+		return ErrMissingDelimiter
+	}
+	return ErrMissingQuote
+}
+
+func (l *lexer) run() {
+	for l.state != nil && !l.open {
+		l.state = l.state(l)
+	}
+}
+
+// result returns the lexed line, along with an error holding ErrInvalidLine when it doesn't match the grammar.
+func (l *lexer) result() (*lexedLine, error) {
+	l.line.text = l.text
+	if l.line.errPos < 0 {
+		return &l.line, nil
+	}
+
+	return &l.line, &ParseError{Column: l.line.errPos + 1, Text: strings.TrimSpace(l.line.text), Err: ErrInvalidLine}
+}
+
+// fail stops the lexing at the current position.
+func (l *lexer) fail() stateFn {
+	l.line.errPos = l.pos
+	return nil
+}
+
+func (l *lexer) skipBlanks() {
+	for l.pos < len(l.text) && isSpace(rune(l.text[l.pos])) {
+		l.pos++
+	}
+}
+
+func (l *lexer) skipKey() {
+	for l.pos < len(l.text) && l.isKeyChar(l.text[l.pos]) {
+		l.pos++
+	}
+}
+
+// commentAt reports whether a comment starts at the index i of the text, following an unquoted value.
+func (l *lexer) commentAt(i int) bool {
+	return l.text[i] == '#' && (!l.spacedComment || i > 0 && isSpace(rune(l.text[i-1])))
+}
+
+// lexStart lexes the indentation and the optional export keyword.
+func lexStart(l *lexer) stateFn {
+	l.skipBlanks()
+	l.line.start = l.pos
+
+	// `export` is a key unless it's followed by another one
+	if l.export && strings.HasPrefix(l.text[l.pos:], "export") {
+		pos := l.pos
+		l.pos += len("export")
+		l.skipBlanks()
+		if l.pos == pos+len("export") || l.pos == len(l.text) || !l.isKeyChar(l.text[l.pos]) {
+			l.pos = pos
+		}
+	}
+
+	return lexKey
+}
+
+func lexKey(l *lexer) stateFn {
+	l.line.keyPos = l.pos
+	l.skipKey()
+	l.line.keyEnd = l.pos
+	if l.pos == l.line.keyPos {
+		return l.fail()
+	}
+
+	// `export KEY` line
+	if l.line.keyPos > l.line.start {
+		l.skipBlanks()
+		if l.pos == len(l.text) {
+			return nil
+		}
+		if l.text[l.pos] == '#' {
+			return lexComment
+		}
+		l.pos = l.line.keyEnd
+	}
+
+	return lexSeparator
+}
+
+func lexSeparator(l *lexer) stateFn {
+	l.line.entry = true
+
+	// the colon has to follow the key, and to be followed by a blank
+	if l.pos+1 < len(l.text) && l.text[l.pos] == ':' && isSpace(rune(l.text[l.pos+1])) && strings.IndexByte(l.separators, ':') >= 0 {
+		l.pos++
+		l.skipBlanks()
+		return lexValue
+	}
+
+	if l.spacedSeparator {
+		l.skipBlanks()
+	}
+	if l.pos < len(l.text) && l.text[l.pos] == '=' && strings.IndexByte(l.separators, '=') >= 0 {
+		l.pos++
+		if l.spacedSeparator {
+			l.skipBlanks()
+		}
+		return lexValue
+	}
+
+	// point right after a misplaced separator
+	if l.pos < len(l.text) && strings.IndexByte(l.separators, l.text[l.pos]) >= 0 {
+		l.pos++
+	}
+	return l.fail()
+}
+
+func lexValue(l *lexer) stateFn {
+	l.line.valuePos = l.pos
+	l.line.valueEnd = l.pos
+	if l.pos == len(l.text) {
+		return nil
+	}
+
+	switch c := l.text[l.pos]; {
+	case l.commentAt(l.pos):
+		return lexComment
+	case l.words:
+		return lexWord
+	case strings.IndexByte(l.quotes, c) >= 0:
+		l.line.quote = c
+		l.pos++
+		return lexQuoted
+	case c == '<' && l.heredocs && l.heredocStart():
+		return lexHeredoc
+	}
+	return lexUnquoted
+}
+
+// heredocStart lexes the opening delimiter of a heredoc value, like <<EOF, <<'EOF' or <<"EOF".
+// It reports whether there is one, followed by blanks and a comment at most.
+func (l *lexer) heredocStart() bool {
+	s := l.text[l.pos:]
+	if !strings.HasPrefix(s, "<<") {
+		return false
+	}
+
+	i := 2
+	var quote byte
+	if i < len(s) && (s[i] == '\'' || s[i] == '"') {
+		quote = s[i]
+		i++
+	}
+	start := i
+	for i < len(s) && isWordChar(s[i]) {
+		i++
+	}
+	delim := s[start:i]
+	if delim == "" {
+		return false
+	}
+	if quote != 0 {
+		if i == len(s) || s[i] != quote {
+			return false
+		}
+		i++
+	}
+
+	pos := l.pos
+	l.pos += i
+	if !l.closing() {
+		l.pos = pos
+		return false
+	}
+
+	l.delim = delim
+	l.line.heredoc = true
+	l.line.quote = quote
+	l.line.valueEnd = l.pos
+	return true
+}
+
+// lexHeredoc lexes the rest of the first line of a heredoc value, then waits for its body.
+func lexHeredoc(l *lexer) stateFn {
+	l.skipBlanks()
+	if l.pos < len(l.text) {
+		l.line.commentPos = l.pos
+		l.pos = len(l.text)
+	}
+
+	l.line.bodyPos = len(l.text)
+	l.open = true
+	return lexHeredocBody
+}
+
+// lexHeredocBody looks for the closing delimiter of a heredoc value on the last line fed,
+// surrounding blanks being ignored.
+func lexHeredocBody(l *lexer) stateFn {
+	eol := strings.LastIndexAny(l.text, "\r\n")
+	if strings.TrimFunc(l.text[eol+1:], isSpace) != l.delim {
+		l.open = true
+		return lexHeredocBody
+	}
+
+	if l.text[eol] == '\n' && l.text[eol-1] == '\r' {
+		eol--
+	}
+	l.line.bodyEnd = max(eol, l.line.bodyPos)
+	l.pos = len(l.text)
+	return nil
+}
+
+// lexQuoted looks for the closing quote of the value.
+// A quote is only closing when it's followed by blanks and a comment at most, so that `KEY='it's'` is read as it's.
+// When no quote closes the value on the line of the opening one while another quote is followed by text,
+// like in `KEY='a'b`, the value is read unquoted.
+func lexQuoted(l *lexer) stateFn {
+	for l.pos < len(l.text) {
+		switch c := l.text[l.pos]; {
+		case c == '\\' && (l.line.quote == '"' || l.line.quote == '\'' && l.singleEscapes):
+			if l.pos+1 == len(l.text) {
+				// the escaped character is on the next line
+				l.open = true
+				return lexQuoted
+			}
+			l.pos += 2
+		case c == l.line.quote:
+			l.pos++
+			if l.closing() {
+				l.line.valueEnd = l.pos
+				return lexTrailing
+			}
+			l.line.stray = true
+		default:
+			l.pos++
+		}
+	}
+
+	if l.line.stray && !strings.ContainsAny(l.text[l.line.valuePos:], "\r\n") {
+		l.line.quote = 0
+		l.pos = l.line.valuePos
+		return lexUnquoted
+	}
+	l.open = true
+	return lexQuoted
+}
+
+// continued reports whether s ends with a backslash which is not doubled.
+func continued(s string) bool {
+	return strings.HasSuffix(s, `\`) && !strings.HasSuffix(s, `\\`)
+}
+
+// eolSize returns the size of the line break at the index i of s, or 0 when there is none.
+func eolSize(s string, i int) int {
+	switch {
+	case strings.HasPrefix(s[i:], "\r\n"):
+		return 2
+	case i < len(s) && (s[i] == '\n' || s[i] == '\r'):
+		return 1
+	}
+	return 0
+}
+
+// closing reports whether the rest of the text, after a quote, holds blanks and a comment at most.
+func (l *lexer) closing() bool {
+	i := l.pos
+	for i < len(l.text) && isSpace(rune(l.text[i])) {
+		i++
+	}
+	if i == len(l.text) {
+		return true
+	}
+
+	return l.text[i] == '#' && strings.IndexByte(l.text[i:], '\n') < 0
+}
+
+// lexUnquoted lexes a value up to a comment or the end of the line, trailing blanks excluded.
+// A backslash ending the line, unless it's doubled, continues the value on the next line.
+func lexUnquoted(l *lexer) stateFn {
+	for l.pos < len(l.text) && !l.commentAt(l.pos) && l.text[l.pos] != '\n' {
+		c := l.text[l.pos]
+		if c == '\\' && l.continuation {
+			if n := eolSize(l.text, l.pos+1); n > 0 {
+				// continued line
+				l.pos += 1 + n
+				continue
+			}
+			if l.pos+1 == len(l.text) && continued(l.text) {
+				l.line.valueEnd = l.pos + 1
+				l.open = true
+				return lexUnquoted
+			}
+		}
+		if c == '\\' && l.shellEscapes && l.pos+1 < len(l.text) {
+			// escaped character
+			l.pos += 2
+			l.line.valueEnd = l.pos
+			continue
+		}
+		if isSpace(rune(c)) {
+			if !l.unquotedBlanks {
+				break
+			}
+		} else {
+			l.line.valueEnd = l.pos + 1
+		}
+		l.pos++
+	}
+
+	if l.pos < len(l.text) && l.text[l.pos] == '\n' {
+		return l.fail()
+	}
+	return lexTrailing
+}
+
+// lexWord lexes a value made of quoted and unquoted parts concatenated, up to a blank outside of quotes.
+// The blanks of the word of a parameter expansion, like ${VAR:-a b}, don't end the value.
+func lexWord(l *lexer) stateFn {
+	for l.pos < len(l.text) {
+		switch c := l.text[l.pos]; {
+		case c == '\\' && l.pos+1 < len(l.text):
+			// escaped character, or continued line
+			l.pos += 1 + max(eolSize(l.text, l.pos+1), 1)
+		case c == '\\' && l.continuation:
+			l.line.valueEnd = l.pos + 1
+			l.open = true
+			return lexWord
+		case strings.IndexByte(l.quotes, c) >= 0:
+			l.line.quote = c
+			l.pos++
+			return lexWordQuoted
+		case c == '$' && strings.HasPrefix(l.text[l.pos:], "${"):
+			l.pos += 2
+			if end := closingBrace(l.text, l.pos); end >= 0 {
+				l.pos = end + 1
+			}
+		case c == '\n':
+			return l.fail()
+		case isSpace(rune(c)):
+			l.line.valueEnd = l.pos
+			return lexTrailing
+		default:
+			l.pos++
+		}
+	}
+
+	l.line.valueEnd = l.pos
+	return lexTrailing
+}
+
+// lexWordQuoted looks for the quote closing a quoted part of a word, which is held by the line until it's found.
+func lexWordQuoted(l *lexer) stateFn {
+	for l.pos < len(l.text) {
+		switch c := l.text[l.pos]; {
+		case c == '\\' && l.line.quote == '"':
+			if l.pos+1 == len(l.text) {
+				// the escaped character is on the next line
+				l.open = true
+				return lexWordQuoted
+			}
+			l.pos += 2
+		case c == l.line.quote:
+			l.line.quote = 0
+			l.pos++
+			return lexWord
+		default:
+			l.pos++
+		}
+	}
+
+	l.open = true
+	return lexWordQuoted
+}
+
+// lexTrailing lexes the blanks and the comment following the value.
+func lexTrailing(l *lexer) stateFn {
+	l.skipBlanks()
+	if l.pos == len(l.text) {
+		return nil
+	}
+	if l.text[l.pos] == '#' {
+		return lexComment
+	}
+	return l.fail()
+}
+
+func lexComment(l *lexer) stateFn {
+	l.line.commentPos = l.pos
+	for ; l.pos < len(l.text); l.pos++ {
+		if l.text[l.pos] == '\n' {
+			return l.fail()
+		}
+	}
+	return nil
+}
+
+func (sx *syntax) isKeyChar(c byte) bool {
+	return isWordChar(c) || strings.IndexByte(sx.keyPunct, c) >= 0
+}
+
+// valuePart is a literal text or a reference to a variable, within a value.
+type valuePart struct {
+	text string      // literal text, when name is empty
+	name string      // name of the variable
+	op   string      // operator of a parameter expansion like ":-", empty for $VAR and ${VAR}
+	word []valuePart // word of a parameter expansion
+}
+
+// lexValueParts splits the unquoted value s into literal texts and references to variables.
+// An escaped dollar sign is literal, along with the reference following it. So is a dollar sign without reference.
+// The escape sequences of esc are decoded as well.
+// It returns the offset of the first unknown escape sequence, which is kept as it is, or -1.
+func (sx *syntax) lexValueParts(s string, esc escaping) ([]valuePart, int) {
+	// a literal text at most around each reference
+	parts := make([]valuePart, 0, 2*strings.Count(s, "$")+1)
+	unknown := -1
+	lit := 0 // start of the current literal text
+	flush := func(end int) {
+		if end > lit {
+			parts = append(parts, valuePart{text: s[lit:end]})
+		}
+	}
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '$':
+			flush(i)
+			_, n, _ := sx.lexReference(s[i+1:], esc)
+			n = max(n, 1)
+			if esc != escapesNone {
+				text, _ := esc.unescape(s[i+1:i+1+n], true)
+				parts = append(parts, valuePart{text: text})
+				lit = i + 1 + n
+			} else {
+				lit = i + 1
+			}
+			i += 1 + n
+		case s[i] == '\\' && esc != escapesNone:
+			text, n := esc.seq(s, i)
+			if n == 0 {
+				if unknown < 0 {
+					unknown = i
+				}
+				i++
+				continue
+			}
+			flush(i)
+			parts = append(parts, valuePart{text: text})
+			i += n
+			lit = i
+		case s[i] == '$':
+			ref, n, u := sx.lexReference(s[i:], esc)
+			if n == 0 {
+				i++
+				continue
+			}
+			if u >= 0 && unknown < 0 {
+				unknown = i + u
+			}
+			flush(i)
+			parts = append(parts, ref)
+			i += n
+			lit = i
+		default:
+			i++
+		}
+	}
+	flush(len(s))
+
+	return parts, unknown
+}
+
+// lexReference lexes the reference to a variable at the start of s, like $VAR, ${VAR} or ${VAR:-word}.
+// It returns the length of the reference, or 0 when s doesn't start with one,
+// along with the offset of the first unknown escape sequence of the word, or -1.
+// Dotted names are only recognized within braces, and only uppercase names with uppercaseNames.
+func (sx *syntax) lexReference(s string, esc escaping) (valuePart, int, int) {
+	if len(s) < 2 || s[0] != '$' {
+		return valuePart{}, 0, -1
+	}
+
+	if s[1] != '{' {
+		n := 1
+		for n < len(s) && sx.isNameChar(s[n], false) {
+			n++
+		}
+		if n == 1 || sx.braced {
+			return valuePart{}, 0, -1
+		}
+		return valuePart{name: s[1:n]}, n, -1
+	}
+
+	n := 2
+	for n < len(s) && sx.isNameChar(s[n], true) {
+		n++
+	}
+	if n == 2 || n == len(s) {
+		return valuePart{}, 0, -1
+	}
+	ref := valuePart{name: s[2:n]}
+	if s[n] == '}' {
+		return ref, n + 1, -1
+	}
+
+	// parameter expansion
+	op := n
+	if s[n] == ':' {
+		n++
+	}
+	if n == len(s) || strings.IndexByte("-=?+", s[n]) < 0 {
+		return valuePart{}, 0, -1
+	}
+	n++
+	ref.op = s[op:n]
+	if !slices.Contains(sx.operators, ref.op) {
+		return valuePart{}, 0, -1
+	}
+
+	end := closingBrace(s, n)
+	if end < 0 {
+		return valuePart{}, 0, -1
+	}
+	word, unknown := sx.lexValueParts(s[n:end], esc)
+	if unknown >= 0 {
+		unknown += n
+	}
+	ref.word = word
+	return ref, end + 1, unknown
+}
+
+// isNameChar reports whether c is accepted in the name of a variable referred to in a value, within braces or not.
+func (sx *syntax) isNameChar(c byte, braced bool) bool {
+	switch {
+	case sx.uppercaseNames:
+		return 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+	case braced:
+		return sx.isKeyChar(c)
+	default:
+		return isWordChar(c)
+	}
+}
+
+// closingBrace returns the index of the brace closing a parameter expansion whose word starts at the index i of s,
+// skipping the references nested in the word. It returns -1 when there is none.
+func closingBrace(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			// escaped character
+			i++
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// escaping is a set of escape sequences decoded in values.
+type escaping int
+
+const (
+	escapesNone     escaping = iota // only escaped dollar signs, for the expansion
+	escapesFull                     // the common escape sequences, unknown ones being reported
+	escapesCompose                  // like escapesFull, unknown ones being kept silently
+	escapesLegacy                   // gotenv 1.6, see legacyUnescape
+	escapesNewlines                 // \n and \r
+	escapesPython                   // \\, \', \", \a, \b, \f, \n, \r, \t and \v
+	escapesShell                    // \`, \", \\ and line breaks, along with \$
+	escapesSingle                   // \\ and \'
+	escapesAll                      // any escaped character, and line breaks
+)
+
+// unescape decodes the escape sequences of s.
+// Escaped dollar signs are decoded as well with dollar, otherwise they are kept for the expansion to read them.
+// It returns the offset of the first unknown escape sequence, which is kept as it is, or -1.
+func (esc escaping) unescape(s string, dollar bool) (string, int) {
+	idx := strings.IndexByte(s, '\\')
+	if idx < 0 {
+		return s, -1
+	}
+
+	unknown := -1
+	var sb strings.Builder
+	sb.Grow(len(s))
+	sb.WriteString(s[:idx])
+	for i := idx; i < len(s); {
+		switch {
+		case s[i] != '\\':
+			sb.WriteByte(s[i])
+			i++
+		case i+1 < len(s) && s[i+1] == '$':
+			if !dollar {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte('$')
+			i += 2
+		default:
+			text, n := esc.seq(s, i)
+			if n == 0 {
+				if unknown < 0 {
+					unknown = i
+				}
+				text, n = `\`, 1
+			}
+			sb.WriteString(text)
+			i += n
+		}
+	}
+	return sb.String(), unknown
+}
+
+// seq decodes the escape sequence starting with the backslash at the index i of s, like \t or \u00e9.
+// It returns the decoded text and the length of the sequence, or 0 when it's unknown.
+func (esc escaping) seq(s string, i int) (string, int) {
+	if i+1 == len(s) {
+		return "", 0
+	}
+
+	c := s[i+1]
+	switch esc {
+	case escapesNewlines:
+		if c != 'n' && c != 'r' {
+			return "", 0
+		}
+	case escapesPython:
+		if strings.IndexByte(`\'"abfnrtv`, c) < 0 {
+			return "", 0
+		}
+		if c == '\'' {
+			return "'", 2
+		}
+	case escapesShell:
+		if strings.IndexByte("`\"\\\n", c) < 0 {
+			return "", 0
+		}
+		if c == '`' {
+			return "`", 2
+		}
+	case escapesSingle:
+		if c != '\\' && c != '\'' {
+			return "", 0
+		}
+		return s[i+1 : i+2], 2
+	case escapesAll:
+		if c == '\n' {
+			// continued line
+			return "", 2
+		}
+		return s[i+1 : i+2], 2
+	}
+
+	return unescapeSeq(s, i)
+}
+
+// unescapeSeq decodes the common escape sequence starting with the backslash at the index i of s.
+// It returns the decoded text and the length of the sequence, or 0 when it's unknown.
+func unescapeSeq(s string, i int) (string, int) {
+	if i+1 == len(s) {
+		return "", 0
+	}
+
+	switch c := s[i+1]; c {
+	case 'a':
+		return "\a", 2
+	case 'b':
+		return "\b", 2
+	case 'f':
+		return "\f", 2
+	case 'n':
+		return "\n", 2
+	case 'r':
+		return "\r", 2
+	case 't':
+		return "\t", 2
+	case 'v':
+		return "\v", 2
+	case '0':
+		return "\x00", 2
+	case '\\', '"':
+		return s[i+1 : i+2], 2
+	case '\n':
+		// continued line
+		return "", 2
+	case 'x', 'u', 'U':
+		size := 2 // hexadecimal digits
+		switch c {
+		case 'u':
+			size = 4
+		case 'U':
+			size = 8
+		}
+		if i+2+size > len(s) {
+			return "", 0
+		}
+		v, err := strconv.ParseUint(s[i+2:i+2+size], 16, 32)
+		if err != nil {
+			return "", 0
+		}
+		if c == 'x' {
+			return string([]byte{byte(v)}), 2 + size
+		}
+		if !utf8.ValidRune(rune(v)) {
+			return "", 0
+		}
+		return string(rune(v)), 2 + size
+	}
+
+	return "", 0
+}
+
+// position returns the line, counted from the first one, and the 1-based column of the offset i of s.
+func position(s string, i int) (int, int) {
+	line := strings.Count(s[:i], "\n")
+	return line, i - strings.LastIndexByte(s[:i], '\n')
+}
+
+// references returns the names of the variables referenced by the parts of a value, including the ones of the words.
+func references(parts []valuePart) []string {
+	var names []string
+	for _, part := range parts {
+		if part.name != "" {
+			names = append(names, part.name)
+			names = append(names, references(part.word)...)
+		}
+	}
+	return names
+}
+
+// assignments returns the names of the variables assigned by the parameter expansions of parts, like ${VAR:=word}.
+func assignments(parts []valuePart) []string {
+	var names []string
+	for _, part := range parts {
+		if part.name == "" {
+			continue
+		}
+		if strings.HasSuffix(part.op, "=") {
+			names = append(names, part.name)
+		}
+		names = append(names, assignments(part.word)...)
+	}
+	return names
+}
+
+func isWordChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
@@ -0,0 +1,116 @@
+package gotenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexer(t *testing.T) {
+	type testCase struct {
+		name    string
+		lines   []string
+		key     string
+		value   string
+		comment string
+		export  bool
+	}
+
+	testCases := []testCase{
+		{"unquoted value", []string{"  KEY = value  # comment"}, "KEY", "value", "# comment", false},
+		{"export keyword", []string{"export KEY=value"}, "KEY", "value", "", true},
+		{"export as a key", []string{"export=value"}, "export", "value", "", false},
+		{"yaml separator", []string{"KEY: 'value'"}, "KEY", "'value'", "", false},
+		{"empty value", []string{"KEY=#comment"}, "KEY", "", "#comment", false},
+		{"escaped quote", []string{`KEY="a\"b" # "c"`}, "KEY", `"a\"b"`, `# "c"`, false},
+		{"multi-line value", []string{`KEY="a`, `b\`, `" c`, `d" # e`}, "KEY", "\"a\nb\\\n\" c\nd\"", "# e", false},
+		{"continued value", []string{`KEY=a \`, `\`, `b # c`}, "KEY", "a \\\n\\\nb", "# c", false},
+		{"backtick quoted value", []string{"KEY=`it's \"a\"` # c"}, "KEY", "`it's \"a\"`", "# c", false},
+		{"heredoc value", []string{"KEY=<<'EOF' # c", "a", " EOF"}, "KEY", "<<'EOF'", "# c", false},
+		{"doubled backslash", []string{`KEY=a\\`}, "KEY", `a\\`, "", false},
+	}
+
+	p := NewParser()
+	l := p.newLexer()
+	for _, tc := range testCases {
+		l.lex(tc.lines[0])
+		for _, line := range tc.lines[1:] {
+			assert.True(t, l.open, tc.name)
+			l.feed("\n" + line)
+		}
+		assert.False(t, l.open, tc.name)
+
+		line, err := l.result()
+		if assert.Nil(t, err, tc.name) {
+			assert.Equal(t, tc.key, line.key(), tc.name)
+			assert.Equal(t, tc.value, line.value(), tc.name)
+			assert.Equal(t, tc.comment, line.comment(), tc.name)
+			assert.Equal(t, tc.export, line.keyPos > line.start, tc.name)
+		}
+	}
+}
+
+func TestLexValueParts(t *testing.T) {
+	type testCase struct {
+		in      string
+		esc     escaping
+		exp     []valuePart
+		unknown int
+	}
+
+	testCases := []testCase{
+		{"plain", escapesNone, []valuePart{{text: "plain"}}, -1},
+		{"$A-${b.c}", escapesNone, []valuePart{{name: "A"}, {text: "-"}, {name: "b.c"}}, -1},
+		{`a\$A $ ${}`, escapesNone, []valuePart{{text: "a"}, {text: "$A $ ${}"}}, -1},
+		{"${A:-${B}/c}", escapesNone, []valuePart{{name: "A", op: ":-", word: []valuePart{{name: "B"}, {text: "/c"}}}}, -1},
+		{"${A?}", escapesNone, []valuePart{{name: "A", op: "?", word: []valuePart{}}}, -1},
+		{`a\tb`, escapesNone, []valuePart{{text: `a\tb`}}, -1},
+		{`a\t$A`, escapesFull, []valuePart{{text: "a"}, {text: "\t"}, {name: "A"}}, -1},
+		{`\$A\"`, escapesFull, []valuePart{{text: "$A"}, {text: `"`}}, -1},
+		{`${A:-\\}`, escapesFull, []valuePart{{name: "A", op: ":-", word: []valuePart{{text: `\`}}}}, -1},
+		{`$A\q`, escapesFull, []valuePart{{name: "A"}, {text: `\q`}}, 2},
+		{`${A:-\q}`, escapesFull, []valuePart{{name: "A", op: ":-", word: []valuePart{{text: `\q`}}}}, 5},
+	}
+
+	sx := NewParser().syntax
+	for _, tc := range testCases {
+		parts, unknown := sx.lexValueParts(tc.in, tc.esc)
+		assert.Equal(t, tc.exp, parts, tc.in)
+		assert.Equal(t, tc.unknown, unknown, tc.in)
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	type testCase struct {
+		in      string
+		esc     escaping
+		dollar  bool
+		exp     string
+		unknown int
+	}
+
+	testCases := []testCase{
+		{"plain", escapesFull, false, "plain", -1},
+		{`\a\b\f\n\r\t\v\0`, escapesFull, false, "\a\b\f\n\r\t\v\x00", -1},
+		{`\\ \"`, escapesFull, false, `\ "`, -1},
+		{`\x41\u00e9\U0001F600`, escapesFull, false, "A\u00e9\U0001F600", -1},
+		{`\$A`, escapesFull, false, `\$A`, -1},
+		{`\$A`, escapesFull, true, `$A`, -1},
+		{`a\q\z`, escapesFull, false, `a\q\z`, 1},
+		{`\x4`, escapesFull, false, `\x4`, 0},
+		{`\uD800`, escapesFull, false, `\uD800`, 0},
+		{`\U00110000`, escapesFull, false, `\U00110000`, 0},
+		{`a\`, escapesFull, false, `a\`, 1},
+		{`\n\r\t`, escapesNewlines, false, "\n\r\\t", 4},
+		{`\'\"\t\0`, escapesPython, false, "'\"\t\\0", 6},
+		{"\\`\\\"\\\\\\\n\\n", escapesShell, false, "`\"\\\\n", 8},
+		{`\'\\\"`, escapesSingle, false, `'\\"`, 4},
+		{"\\a\\ \\\n", escapesAll, false, "a ", -1},
+	}
+
+	for _, tc := range testCases {
+		val, unknown := tc.esc.unescape(tc.in, tc.dollar)
+		assert.Equal(t, tc.exp, val, tc.in)
+		assert.Equal(t, tc.unknown, unknown, tc.in)
+	}
+}
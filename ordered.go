@@ -0,0 +1,107 @@
+package gotenv
+
+import (
+	"io"
+	"iter"
+	"strings"
+)
+
+// OrderedEnv holds key/value pair of valid environment variable in the order they are declared.
+// A variable declared more than once keeps the position of its first declaration and the value of its last one.
+// The zero value is an empty OrderedEnv ready to use.
+type OrderedEnv struct {
+	keys []string
+	env  Env
+}
+
+// NewOrderedEnv returns an empty OrderedEnv.
+func NewOrderedEnv() *OrderedEnv {
+	return &OrderedEnv{env: make(Env)}
+}
+
+// Get returns the value of the variable and whether it's present.
+func (o *OrderedEnv) Get(key string) (string, bool) {
+	val, ok := o.env[key]
+	return val, ok
+}
+
+// Set sets the value of the variable, appending it when it's not present yet.
+func (o *OrderedEnv) Set(key, val string) {
+	if o.env == nil {
+		o.env = make(Env)
+	}
+	if _, ok := o.env[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.env[key] = val
+}
+
+// Delete removes the variable.
+func (o *OrderedEnv) Delete(key string) {
+	if _, ok := o.env[key]; !ok {
+		return
+	}
+	delete(o.env, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Len returns the number of variables.
+func (o *OrderedEnv) Len() int {
+	return len(o.keys)
+}
+
+// Keys returns the names of the variables in declaration order.
+func (o *OrderedEnv) Keys() []string {
+	return append([]string(nil), o.keys...)
+}
+
+// All returns an iterator over the variables in declaration order.
+func (o *OrderedEnv) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, k := range o.keys {
+			if !yield(k, o.env[k]) {
+				return
+			}
+		}
+	}
+}
+
+// Env returns the variables as an Env.
+func (o *OrderedEnv) Env() Env {
+	env := make(Env, len(o.keys))
+	for k, v := range o.env {
+		env[k] = v
+	}
+	return env
+}
+
+// ParseOrdered is like Parse but it keeps the variables in declaration order.
+func ParseOrdered(r io.Reader) *OrderedEnv {
+	env, _ := NewParser(WithStrict(false)).parse(r)
+	return env
+}
+
+// StrictParseOrdered is like StrictParse but it keeps the variables in declaration order.
+func StrictParseOrdered(r io.Reader) (*OrderedEnv, error) {
+	return NewParser().parse(r)
+}
+
+// ReadOrdered is like Read but it keeps the variables in declaration order.
+func ReadOrdered(filename string) (*OrderedEnv, error) {
+	return NewParser().parseFile(nil, filename)
+}
+
+// MarshalOrdered outputs the given environment as a env file.
+// Unlike Marshal, variables are written in declaration order.
+func MarshalOrdered(env *OrderedEnv) (string, error) {
+	lines := make([]string, 0, env.Len())
+	for k, v := range env.All() {
+		lines = append(lines, marshalLine(k, v))
+	}
+	return strings.Join(lines, "\n"), nil
+}
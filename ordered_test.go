@@ -0,0 +1,69 @@
+package gotenv_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+func TestParseOrdered(t *testing.T) {
+	env := gotenv.ParseOrdered(strings.NewReader("ZED=1\nALPHA=2\nlol$wut\nMIDDLE=3\nZED=4"))
+
+	assert.Equal(t, []string{"ZED", "ALPHA", "MIDDLE"}, env.Keys())
+	assert.Equal(t, 3, env.Len())
+	assert.Equal(t, gotenv.Env{"ZED": "4", "ALPHA": "2", "MIDDLE": "3"}, env.Env())
+
+	val, ok := env.Get("ZED")
+	assert.True(t, ok)
+	assert.Equal(t, "4", val)
+
+	var keys, vals []string
+	for k, v := range env.All() {
+		keys = append(keys, k)
+		vals = append(vals, v)
+		if k == "ALPHA" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"ZED", "ALPHA"}, keys)
+	assert.Equal(t, []string{"4", "2"}, vals)
+}
+
+func TestStrictParseOrdered(t *testing.T) {
+	env, err := gotenv.StrictParseOrdered(strings.NewReader("B=1\nA=$B"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"B", "A"}, env.Keys())
+
+	_, err = gotenv.StrictParseOrdered(strings.NewReader("B=1\nlol$wut"))
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+}
+
+func TestReadOrdered(t *testing.T) {
+	env, err := gotenv.ReadOrdered("fixtures/plain.env")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"OPTION_A", "OPTION_B", "OPTION_C", "OPTION_D", "OPTION_E"}, env.Keys())
+
+	_, err = gotenv.ReadOrdered(".env.not.exist")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestOrderedEnv(t *testing.T) {
+	var env gotenv.OrderedEnv
+	env.Set("B", "1")
+	env.Set("A", "2")
+	env.Set("C", "3")
+	env.Set("B", "4")
+	env.Delete("A")
+	env.Delete("NOPE")
+
+	assert.Equal(t, []string{"B", "C"}, env.Keys())
+	_, ok := env.Get("A")
+	assert.False(t, ok)
+
+	actual, err := gotenv.MarshalOrdered(&env)
+	assert.Nil(t, err)
+	assert.Equal(t, "B=4\nC=3", actual)
+}
@@ -0,0 +1,319 @@
+package gotenv
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// LookupFunc retrieves the value of the variable named by the key, reporting whether it's present.
+// os.LookupEnv is a LookupFunc.
+type LookupFunc func(key string) (string, bool)
+
+// Lookup retrieves the value of the variable named by the key, reporting whether it's present.
+// It can be used as a LookupFunc to expand variables from another file.
+func (env Env) Lookup(key string) (string, bool) {
+	val, ok := env[key]
+	return val, ok
+}
+
+// Parser parses env files with a custom configuration. Use NewParser to create one.
+// A Parser is safe for concurrent use.
+type Parser struct {
+	syntax
+	strict          bool
+	override        bool
+	expansion       bool
+	strictExpansion bool
+	forwardRefs     bool
+	lookup          LookupFunc
+	warn            func(error)
+	maxSize         int
+}
+
+// Option configures a Parser.
+type Option func(*Parser)
+
+// NewParser returns a Parser configured with the given options.
+// Without any option, it behaves like StrictParse.
+func NewParser(opts ...Option) *Parser {
+	p := &Parser{
+		syntax:    dialects[DialectGotenv].syntax,
+		strict:    true,
+		expansion: true,
+		lookup:    os.LookupEnv,
+		maxSize:   DefaultMaxValueSize,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithStrict sets whether parsing stops at the first invalid line, which is the default.
+// When it's false, invalid lines are skipped and every one of them is returned in the error, joined with errors.Join,
+// along with the valid variables.
+func WithStrict(strict bool) Option {
+	return func(p *Parser) {
+		p.strict = strict
+	}
+}
+
+// WithOverride sets whether the variables of the file take precedence over the existing environment variables,
+// both when expanding values and when applying them to the environment. It's the difference between OverLoad and Load.
+func WithOverride(override bool) Option {
+	return func(p *Parser) {
+		p.override = override
+	}
+}
+
+// WithExpansion sets whether variables are expanded in values, which is the default.
+// When it's false, dollar signs are kept as they are written.
+func WithExpansion(expansion bool) Option {
+	return func(p *Parser) {
+		p.expansion = expansion
+	}
+}
+
+// WithLegacyEscapes sets whether double quoted values are unescaped the way of gotenv 1.6:
+// \n and \r are converted and the backslash is removed in front of any other character but $, so that "\t" reads as t.
+// By default, the common escape sequences are decoded, like \t, \\, \", \0, \xHH, \uHHHH and \U00HHHHHH,
+// and an unknown escape sequence is an error holding ErrUnknownEscape, or a warning when the parser isn't strict.
+func WithLegacyEscapes(legacy bool) Option {
+	return func(p *Parser) {
+		p.escapes = escapesFull
+		if legacy {
+			p.escapes = escapesLegacy
+		}
+	}
+}
+
+// WithUppercaseOnlyExpansion sets whether only the variables named with uppercase letters, digits and underscores
+// are expanded, the way of gotenv 1.6, so that the dollar sign of a value like pa$word is kept as it is written.
+// By default, any name accepted as a key is expanded, whatever its case.
+func WithUppercaseOnlyExpansion(uppercase bool) Option {
+	return func(p *Parser) {
+		p.uppercaseNames = uppercase
+	}
+}
+
+// WithStrictExpansion sets whether referring to a variable which is defined neither earlier in the file
+// nor by the lookup function is an error, holding ErrUndefinedVar. By default, such variables expand to an empty string.
+// The variables of the parameter expansions handling unset variables, like ${VAR:-default}, are not concerned.
+func WithStrictExpansion(strict bool) Option {
+	return func(p *Parser) {
+		p.strictExpansion = strict
+	}
+}
+
+// WithForwardReferences sets whether values can refer to variables declared later in the file.
+// Every line is parsed before the values are expanded, following the references between variables
+// regardless of the order they are declared in, the ones assigned by ${VAR:=word} included. Variables referring
+// to each other are reported as a ParseError holding a *CycleError, as well as the variables referring to them.
+// The variables referring to a line in error are skipped, the error being reported for that line only.
+// A variable referring to itself, like PATH=$PATH:/bin, gets its previous value.
+func WithForwardReferences(enabled bool) Option {
+	return func(p *Parser) {
+		p.forwardRefs = enabled
+	}
+}
+
+// WithWarnings sets a function called with the problems which don't prevent a line from being parsed,
+// such as the references to undefined variables when the expansion is not strict.
+// The problems are ParseError values.
+func WithWarnings(fn func(error)) Option {
+	return func(p *Parser) {
+		p.warn = fn
+	}
+}
+
+// WithLookup sets the function retrieving the variables expanded in values which are not declared earlier in the file.
+// It defaults to os.LookupEnv. A nil function only expands variables from the file, making the parsing hermetic.
+func WithLookup(fn LookupFunc) Option {
+	return func(p *Parser) {
+		p.lookup = fn
+	}
+}
+
+// WithSeparators sets the characters accepted between keys and values, among '=' and ':'.
+// The colon, YAML style, has to be followed by a whitespace. It defaults to both.
+func WithSeparators(separators string) Option {
+	return func(p *Parser) {
+		p.separators = separators
+	}
+}
+
+// WithExport sets whether the `export` keyword is accepted in front of variables, which is the default.
+func WithExport(export bool) Option {
+	return func(p *Parser) {
+		p.export = export
+	}
+}
+
+// WithContinuation sets whether an unquoted value ending with a backslash is continued on the next line,
+// which is the default. When it's false, the backslash is kept as it is written, like in DIR=C:\tmp\.
+func WithContinuation(continuation bool) Option {
+	return func(p *Parser) {
+		p.continuation = continuation
+	}
+}
+
+// DefaultMaxValueSize is the maximum size of a line, in bytes, unless set with WithMaxValueSize.
+const DefaultMaxValueSize = 1 << 20
+
+// WithMaxValueSize sets the maximum size of a line, or of a value spanning several lines, in bytes.
+// Longer lines are reported as a ParseError holding ErrLineTooLong. A size of 0 or less removes the limit.
+// It defaults to DefaultMaxValueSize.
+func WithMaxValueSize(size int) Option {
+	return func(p *Parser) {
+		p.maxSize = size
+	}
+}
+
+// Parse parses line by line any io.Reader supplied and returns the Env key/value pair of valid variables.
+func (p *Parser) Parse(r io.Reader) (Env, error) {
+	env, err := p.parse(r)
+	return env.env, err
+}
+
+// ParseFile parses the file line by line and returns the Env key/value pair of valid variables.
+// The errors of invalid lines hold the filename.
+func (p *Parser) ParseFile(filename string) (Env, error) {
+	env, err := p.parseFile(nil, filename)
+	if env == nil {
+		return nil, err
+	}
+	return env.env, err
+}
+
+// ParseFS is like ParseFile but it reads the file from fsys.
+func (p *Parser) ParseFS(fsys fs.FS, filename string) (Env, error) {
+	env, err := p.parseFile(fsys, filename)
+	if env == nil {
+		return nil, err
+	}
+	return env.env, err
+}
+
+// Apply parses any io.Reader supplied and sets the valid variables as environment variables.
+// Existing environment variables are only overridden with the WithOverride option.
+func (p *Parser) Apply(r io.Reader) error {
+	env, err := p.parse(r)
+	return p.apply(env, err)
+}
+
+// Load parses the files and sets the valid variables as environment variables, like Apply.
+// When it's called with no argument, it loads the `.env` file on the current path.
+// When it's not strict, the files following one with invalid lines are loaded as well.
+func (p *Parser) Load(filenames ...string) error {
+	_, _, err := p.load(nil, defaultFiles(filenames), false)
+	return err
+}
+
+// LoadFS is like Load but it reads the files from fsys.
+func (p *Parser) LoadFS(fsys fs.FS, filenames ...string) error {
+	_, _, err := p.load(fsys, defaultFiles(filenames), false)
+	return err
+}
+
+// LoadOptional is like Load but it skips the missing files, and returns their names.
+// Other errors, like a denied permission or an invalid line, are still returned.
+func (p *Parser) LoadOptional(filenames ...string) ([]string, error) {
+	_, skipped, err := p.load(nil, defaultFiles(filenames), true)
+	return skipped, err
+}
+
+// defaultFiles returns the files to load, which is the `.env` file when there is none.
+func defaultFiles(filenames []string) []string {
+	if len(filenames) == 0 {
+		return []string{".env"}
+	}
+	return filenames
+}
+
+// load sets the variables of the files read from fsys, or from the OS when fsys is nil.
+// The missing files are skipped when they're optional. It returns the names of the files loaded and the ones skipped,
+// a file being loaded when its valid variables are set, in spite of its invalid lines.
+// When it's not strict, the invalid lines of every file are reported, joined with errors.Join.
+func (p *Parser) load(fsys fs.FS, filenames []string, optional bool) (loaded, skipped []string, err error) {
+	var errs []error
+	for _, filename := range filenames {
+		env, err := p.parseFile(fsys, filename)
+		if env == nil {
+			if optional && errors.Is(err, fs.ErrNotExist) {
+				skipped = append(skipped, filename)
+				continue
+			}
+			return loaded, skipped, errors.Join(append(errs, err)...)
+		}
+		if err := p.apply(env, err); err != nil {
+			if p.strict {
+				return loaded, skipped, err
+			}
+			errs = append(errs, unjoin(err)...)
+		}
+		loaded = append(loaded, filename)
+	}
+
+	return loaded, skipped, errors.Join(errs...)
+}
+
+// apply sets the variables of env as environment variables, unless err is the error of a strict parsing.
+// The values holding a NUL character, which the environment can't hold, are reported along with err.
+func (p *Parser) apply(env *OrderedEnv, err error) error {
+	if err != nil && p.strict {
+		return err
+	}
+
+	var invalid []error
+	for key, val := range env.All() {
+		if strings.IndexByte(val, 0) >= 0 {
+			invalid = append(invalid, &varError{name: key, msg: "value holding a NUL character", err: ErrUnrepresentable})
+		}
+	}
+	if len(invalid) > 0 {
+		if p.strict {
+			return invalid[0]
+		}
+		err = errors.Join(append(unjoin(err), invalid...)...)
+	}
+
+	for key, val := range env.All() {
+		if strings.IndexByte(val, 0) < 0 {
+			setenv(key, val, p.override)
+		}
+	}
+
+	return err
+}
+
+// parseFile parses the file read from fsys, or from the OS when fsys is nil.
+func (p *Parser) parseFile(fsys fs.FS, filename string) (*OrderedEnv, error) {
+	f, err := openFile(fsys, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if warn := p.warn; warn != nil {
+		// a copy of the parser to report the filename along with the warnings
+		c := *p
+		c.warn = func(err error) {
+			warn(withFilename(err, filename))
+		}
+		p = &c
+	}
+
+	env, err := p.parse(f)
+	return env, withFilename(err, filename)
+}
+
+func openFile(fsys fs.FS, name string) (fs.File, error) {
+	if fsys == nil {
+		return os.Open(name)
+	}
+	return fsys.Open(name)
+}
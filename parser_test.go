@@ -0,0 +1,409 @@
+package gotenv_test
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+func TestParser_lookup(t *testing.T) {
+	defer os.Clearenv()
+	os.Setenv("HOME", "/home/gopher")
+
+	in := "URL=${SCHEME:-http}://$HOST/\nDIR=$HOME"
+
+	tests := []struct {
+		name   string
+		opts   []gotenv.Option
+		result gotenv.Env
+	}{
+		{
+			"defaults to the environment variables",
+			nil,
+			gotenv.Env{"URL": "http:///", "DIR": "/home/gopher"},
+		},
+		{
+			"expands from a fixed map",
+			[]gotenv.Option{gotenv.WithLookup(gotenv.Env{"SCHEME": "https", "HOST": "example.com"}.Lookup)},
+			gotenv.Env{"URL": "https://example.com/", "DIR": ""},
+		},
+		{
+			"expands from os.LookupEnv",
+			[]gotenv.Option{gotenv.WithLookup(os.LookupEnv)},
+			gotenv.Env{"URL": "http:///", "DIR": "/home/gopher"},
+		},
+		{
+			"is hermetic without lookup",
+			[]gotenv.Option{gotenv.WithLookup(nil)},
+			gotenv.Env{"URL": "http:///", "DIR": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		env, err := gotenv.NewParser(tt.opts...).Parse(strings.NewReader(in))
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.result, env, tt.name)
+	}
+}
+
+func TestParser_lookupPrecedence(t *testing.T) {
+	lookup := gotenv.Env{"A": "fromLookup"}.Lookup
+	p := gotenv.NewParser(gotenv.WithLookup(lookup))
+
+	env, err := p.Parse(strings.NewReader("A=fromFile\nB=$A\nC=${C:?required}"))
+	assert.ErrorIs(t, err, gotenv.ErrRequiredVar)
+	assert.Equal(t, gotenv.Env{"A": "fromFile", "B": "fromLookup"}, env)
+}
+
+func TestParser_options(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []gotenv.Option
+		in     string
+		result gotenv.Env
+		err    string
+	}{
+		{
+			"is strict by default",
+			nil,
+			"A=1\nlol$wut\nB=2",
+			gotenv.Env{"A": "1"},
+			"2:4: line `lol$wut` doesn't match format",
+		},
+		{
+			"skips invalid lines when not strict",
+			[]gotenv.Option{gotenv.WithStrict(false)},
+			"A=1\nlol$wut\nB=2\nC",
+			gotenv.Env{"A": "1", "B": "2"},
+			"2:4: line `lol$wut` doesn't match format\n4:2: line `C` doesn't match format",
+		},
+		{
+			"keeps dollar signs without expansion",
+			[]gotenv.Option{gotenv.WithExpansion(false)},
+			"A=1\nB=$A\nC=\"${A:-x}\\$A\"",
+			gotenv.Env{"A": "1", "B": "$A", "C": `${A:-x}\$A`},
+			"",
+		},
+		{
+			"accepts only the given separators",
+			[]gotenv.Option{gotenv.WithSeparators("=")},
+			"A=1\nB: 2",
+			gotenv.Env{"A": "1"},
+			"2:2: line `B: 2` doesn't match format",
+		},
+		{
+			"accepts the YAML separator alone",
+			[]gotenv.Option{gotenv.WithSeparators(":")},
+			"A: 1\nB=2",
+			gotenv.Env{"A": "1"},
+			"2:2: line `B=2` doesn't match format",
+		},
+		{
+			"unescapes double quoted values the legacy way",
+			[]gotenv.Option{gotenv.WithLegacyEscapes(true)},
+			"A=\"a\\tb\\nc\\\\$d\\\"\"",
+			gotenv.Env{"A": "atb\nc$d\""},
+			"",
+		},
+		{
+			"expands names whatever their case",
+			[]gotenv.Option{gotenv.WithLookup(nil)},
+			"word=x\nA=pa$word\nB=${word}\nC=$WORD",
+			gotenv.Env{"word": "x", "A": "pax", "B": "x", "C": ""},
+			"",
+		},
+		{
+			"expands only uppercase names the legacy way",
+			[]gotenv.Option{gotenv.WithLookup(nil), gotenv.WithUppercaseOnlyExpansion(true)},
+			"word=x\nWORD=y\nA=pa$word\nB=${word}\nC=$WORDs\nD=${db.host:-z}\nE=${WORD:-$word}",
+			gotenv.Env{"word": "x", "WORD": "y", "A": "pa$word", "B": "${word}", "C": "ys", "D": "${db.host:-z}", "E": "y"},
+			"",
+		},
+		{
+			"keeps unknown escape sequences when not strict",
+			[]gotenv.Option{gotenv.WithStrict(false)},
+			"A=\"a\\qb\"\nB=2",
+			gotenv.Env{"A": `a\qb`, "B": "2"},
+			"",
+		},
+		{
+			"continues unquoted values ending with a backslash",
+			nil,
+			"DIR=C:\\tmp\\\nB=1",
+			gotenv.Env{"DIR": "C:\\tmpB=1"},
+			"",
+		},
+		{
+			"keeps the trailing backslash without continuation",
+			[]gotenv.Option{gotenv.WithContinuation(false)},
+			"DIR=C:\\tmp\\\nB=1\nC=\"a\\\nb\"",
+			gotenv.Env{"DIR": "C:\\tmp\\", "B": "1", "C": "ab"},
+			"",
+		},
+		{
+			"rejects the export keyword",
+			[]gotenv.Option{gotenv.WithExport(false)},
+			"export A=1",
+			gotenv.Env{},
+			"1:8: line `export A=1` doesn't match format",
+		},
+	}
+
+	for _, tt := range tests {
+		env, err := gotenv.NewParser(tt.opts...).Parse(strings.NewReader(tt.in))
+		if tt.err == "" {
+			assert.Nil(t, err, tt.name)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.name)
+		}
+		assert.Equal(t, tt.result, env, tt.name)
+	}
+}
+
+func TestParser_WithMaxValueSize(t *testing.T) {
+	p := gotenv.NewParser(gotenv.WithMaxValueSize(16))
+
+	_, err := p.Parse(strings.NewReader("A=" + strings.Repeat("a", 14)))
+	assert.Nil(t, err)
+
+	_, err = p.Parse(strings.NewReader("A=" + strings.Repeat("a", 15)))
+	assert.EqualError(t, err, "1:1: line `A=aaaaaaaaaaaaaaa...` exceeds the maximum size")
+	assert.ErrorIs(t, err, gotenv.ErrLineTooLong)
+
+	// long lines are skipped when not strict, even within a multi-line value
+	p = gotenv.NewParser(gotenv.WithMaxValueSize(16), gotenv.WithStrict(false))
+	env, err := p.Parse(strings.NewReader("A=1\nB=" + strings.Repeat("b", 64) + "\r\nC=\"2\n" + strings.Repeat("c", 64) + "\"\nD=\"12345\n67890\n12345\"\nE=" + strings.Repeat("e", 64)))
+	assert.EqualError(t, err, "2:1: line `B=bbbbbbbbbbbbbbbb...` exceeds the maximum size\n"+
+		"3:1: line `C=\"2...` exceeds the maximum size\n"+
+		"5:1: line `D=\"12345\n67890\n12345\"...` exceeds the maximum size\n"+
+		"8:1: line `E=eeeeeeeeeeeeeeee...` exceeds the maximum size")
+	assert.Equal(t, gotenv.Env{"A": "1"}, env)
+
+	// without limit
+	long := strings.Repeat("x", 1<<20)
+	env, err = gotenv.NewParser(gotenv.WithMaxValueSize(0)).Parse(strings.NewReader("A=" + long + "\nB=1"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": long, "B": "1"}, env)
+
+	// the default limit is larger than bufio.MaxScanTokenSize
+	env, err = gotenv.StrictParse(strings.NewReader("A=" + long[:bufio.MaxScanTokenSize]))
+	assert.Nil(t, err)
+	assert.Len(t, env["A"], bufio.MaxScanTokenSize)
+}
+
+func TestParser_ParseFile(t *testing.T) {
+	p := gotenv.NewParser()
+
+	env, err := p.ParseFile("fixtures/plain.env")
+	assert.Nil(t, err)
+	assert.Equal(t, "5", env["OPTION_E"])
+
+	_, err = p.ParseFile(".env.invalid")
+	assert.EqualError(t, err, ".env.invalid:1:4: line `lol$wut` doesn't match format")
+
+	_, err = p.ParseFile(".env.not.exist")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestParser_ParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/.env": {Data: []byte("A=1\nB=${A}2")},
+		"bad.env":  {Data: []byte("A=1\nlol$wut")},
+	}
+	p := gotenv.NewParser()
+
+	env, err := p.ParseFS(fsys, "app/.env")
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": "1", "B": "12"}, env)
+
+	_, err = p.ParseFS(fsys, "bad.env")
+	assert.EqualError(t, err, "bad.env:2:4: line `lol$wut` doesn't match format")
+
+	_, err = p.ParseFS(fsys, "nope.env")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestParser_Apply(t *testing.T) {
+	defer os.Clearenv()
+	os.Setenv("A", "fromEnv")
+
+	err := gotenv.NewParser().Apply(strings.NewReader("A=fromFile\nB=$A"))
+	assert.Nil(t, err)
+	assert.Equal(t, "fromEnv", os.Getenv("A"))
+	assert.Equal(t, "fromEnv", os.Getenv("B"))
+
+	err = gotenv.NewParser(gotenv.WithOverride(true)).Apply(strings.NewReader("A=fromFile\nB=$A"))
+	assert.Nil(t, err)
+	assert.Equal(t, "fromFile", os.Getenv("A"))
+	assert.Equal(t, "fromFile", os.Getenv("B"))
+
+	// invalid lines are skipped but reported when not strict
+	err = gotenv.NewParser(gotenv.WithStrict(false)).Apply(strings.NewReader("lol$wut\nC=3"))
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.Equal(t, "3", os.Getenv("C"))
+
+	err = gotenv.NewParser().Apply(strings.NewReader("lol$wut\nD=4"))
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.Equal(t, "", os.Getenv("D"))
+
+	// the environment can't hold NUL characters
+	err = gotenv.NewParser().Apply(strings.NewReader("E=5\nNUL=\"a\\0b\""))
+	assert.EqualError(t, err, "can't be represented: NUL: value holding a NUL character")
+	assert.ErrorIs(t, err, gotenv.ErrUnrepresentable)
+	_, ok := os.LookupEnv("E")
+	assert.False(t, ok)
+
+	err = gotenv.NewParser(gotenv.WithStrict(false)).Apply(strings.NewReader("NUL=\"\\x00\"\nlol$wut\nE=5"))
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.ErrorIs(t, err, gotenv.ErrUnrepresentable)
+	assert.Equal(t, "5", os.Getenv("E"))
+	_, ok = os.LookupEnv("NUL")
+	assert.False(t, ok)
+}
+
+func TestParser_Load(t *testing.T) {
+	defer os.Clearenv()
+
+	err := gotenv.NewParser(gotenv.WithLookup(nil)).Load("fixtures/plain.env", "fixtures/yaml.env")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", os.Getenv("OPTION_A"))
+	assert.Equal(t, "5", os.Getenv("OPTION_E"))
+
+	err = gotenv.NewParser().Load("fixtures/plain.env", ".env.invalid")
+	assert.EqualError(t, err, ".env.invalid:1:4: line `lol$wut` doesn't match format")
+}
+
+func TestParser_WithStrictExpansion(t *testing.T) {
+	defer os.Clearenv()
+	os.Setenv("HOME", "/home/gopher")
+
+	p := gotenv.NewParser(gotenv.WithStrictExpansion(true))
+
+	env, err := p.Parse(strings.NewReader("DATABASE_URL=postgres://db\nDIR=$HOME\nPORT=${PORT:-5432}\nURL=\"${DATABSE_URL}\""))
+	assert.EqualError(t, err, "4:6: line `URL=\"${DATABSE_URL}\"` has an undefined variable: DATABSE_URL")
+	assert.ErrorIs(t, err, gotenv.ErrUndefinedVar)
+	assert.Equal(t, gotenv.Env{"DATABASE_URL": "postgres://db", "DIR": "/home/gopher", "PORT": "5432"}, env)
+
+	var pe *gotenv.ParseError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.Equal(t, 4, pe.Line)
+	}
+
+	// reports every undefined variable when not strict
+	p = gotenv.NewParser(gotenv.WithStrictExpansion(true), gotenv.WithStrict(false), gotenv.WithLookup(nil))
+	env, err = p.Parse(strings.NewReader("A=$HOME\nB=1\nC=${B}${NOPE:-x}$NOPE"))
+	assert.EqualError(t, err, "1:3: line `A=$HOME` has an undefined variable: HOME\n3:17: line `C=${B}${NOPE:-x}$NOPE` has an undefined variable: NOPE")
+	assert.Equal(t, gotenv.Env{"B": "1"}, env)
+}
+
+func TestParser_WithWarnings(t *testing.T) {
+	var warnings []error
+	p := gotenv.NewParser(gotenv.WithLookup(nil), gotenv.WithWarnings(func(err error) {
+		warnings = append(warnings, err)
+	}))
+
+	env, err := p.Parse(strings.NewReader("A=1\nB=$A$NOPE\nC=${B:-$NEVER}${D:=$SET}"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": "1", "B": "1", "C": "1", "D": ""}, env)
+	if assert.Len(t, warnings, 2) {
+		assert.EqualError(t, warnings[0], "2:5: line `B=$A$NOPE` has an undefined variable: NOPE")
+		assert.EqualError(t, warnings[1], "3:20: line `C=${B:-$NEVER}${D:=$SET}` has an undefined variable: SET")
+		assert.ErrorIs(t, warnings[0], gotenv.ErrUndefinedVar)
+	}
+
+	warnings = nil
+	_, err = gotenv.NewParser(gotenv.WithStrict(false), gotenv.WithWarnings(func(err error) {
+		warnings = append(warnings, err)
+	})).Parse(strings.NewReader(`A="a\qb\z"`))
+	assert.Nil(t, err)
+	if assert.Len(t, warnings, 1) {
+		assert.EqualError(t, warnings[0], "1:5: line `A=\"a\\qb\\z\"` has an unknown escape sequence")
+		assert.ErrorIs(t, warnings[0], gotenv.ErrUnknownEscape)
+	}
+
+	warnings = nil
+	_, err = p.ParseFS(fstest.MapFS{".env": {Data: []byte("A=$NOPE")}}, ".env")
+	assert.Nil(t, err)
+	if assert.Len(t, warnings, 1) {
+		assert.EqualError(t, warnings[0], ".env:1:3: line `A=$NOPE` has an undefined variable: NOPE")
+	}
+}
+
+func TestParser_WithForwardReferences(t *testing.T) {
+	p := gotenv.NewParser(gotenv.WithForwardReferences(true), gotenv.WithLookup(nil))
+
+	env, err := p.ParseFile("fixtures/vars.env")
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"A": "fromFile", "B": "fromFile", "C": "fromFile", "D": "fromFile"}, env)
+
+	env, err = p.Parse(strings.NewReader("URL=${SCHEME}://${HOST:-localhost}:$PORT\nPORT=80\nPORT=${PORT}80\nSCHEME='$http'\nC=${D:=$PORT}"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"URL": "$http://localhost:8080", "PORT": "8080", "SCHEME": "$http", "C": "8080", "D": "8080"}, env)
+
+	env, err = gotenv.NewParser(gotenv.WithForwardReferences(true), gotenv.WithLookup(gotenv.Env{"PATH": "/bin"}.Lookup)).Parse(strings.NewReader("PATH=$PATH:$BIN\nBIN=/opt/bin"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"PATH": "/bin:/opt/bin", "BIN": "/opt/bin"}, env)
+
+	// the variables assigned by a parameter expansion are declared like in the default mode
+	for _, p := range []*gotenv.Parser{p, gotenv.NewParser(gotenv.WithLookup(nil))} {
+		env, err = p.Parse(strings.NewReader("A=${X:=1}\nB=$X\nC=${Y=$B}${Y}"))
+		assert.Nil(t, err)
+		assert.Equal(t, gotenv.Env{"A": "1", "X": "1", "B": "1", "C": "11", "Y": "1"}, env)
+	}
+
+	env, err = p.Parse(strings.NewReader("B=$X\nA=${Z:-${X:=$C}}\nC=3\nD=${X:=4}"))
+	assert.Nil(t, err)
+	assert.Equal(t, gotenv.Env{"B": "3", "A": "3", "X": "3", "C": "3", "D": "3"}, env)
+}
+
+func TestParser_WithForwardReferences_failedReference(t *testing.T) {
+	// the error is reported by the declaration in error, not by the variables referring to it
+	p := gotenv.NewParser(gotenv.WithForwardReferences(true), gotenv.WithLookup(nil), gotenv.WithStrictExpansion(true))
+	env, err := p.Parse(strings.NewReader("A=$B\nB=$NOPE\nC=1"))
+	assert.EqualError(t, err, "2:3: line `B=$NOPE` has an undefined variable: NOPE")
+	assert.Equal(t, gotenv.Env{}, env)
+
+	p = gotenv.NewParser(gotenv.WithForwardReferences(true), gotenv.WithLookup(nil), gotenv.WithStrict(false))
+	env, err = p.Parse(strings.NewReader("A=$B\nB=${NOPE:?x}\nC=x$A\nD=${Y:=$B}\nE=$Y\nF=1"))
+	assert.EqualError(t, err, "2:3: line `B=${NOPE:?x}` has an unset required variable: NOPE: x")
+	assert.Equal(t, gotenv.Env{"F": "1"}, env)
+}
+
+func TestParser_WithForwardReferences_cycle(t *testing.T) {
+	p := gotenv.NewParser(gotenv.WithForwardReferences(true), gotenv.WithLookup(nil))
+
+	env, err := p.Parse(strings.NewReader("X=1\nA=$B\nB=${C:-x}\nC=\"$A\"\nD=$A$X"))
+	assert.EqualError(t, err, "2:3: line `A=$B` has a reference cycle: A -> B -> C -> A")
+	assert.Equal(t, gotenv.Env{"X": "1"}, env)
+
+	var cycle *gotenv.CycleError
+	if assert.ErrorAs(t, err, &cycle) {
+		assert.Equal(t, []string{"A", "B", "C", "A"}, cycle.Path)
+	}
+
+	// every problem is reported in line order when not strict
+	p = gotenv.NewParser(gotenv.WithForwardReferences(true), gotenv.WithLookup(nil), gotenv.WithStrict(false), gotenv.WithStrictExpansion(true))
+	env, err = p.Parse(strings.NewReader("A=$B\nlol$wut\nB=$A\nC=$NOPE\nD=$A$X\nSELF=$SELF"))
+	assert.EqualError(t, err, "1:3: line `A=$B` has a reference cycle: A -> B -> A\n"+
+		"2:4: line `lol$wut` doesn't match format\n"+
+		"4:3: line `C=$NOPE` has an undefined variable: NOPE\n"+
+		"5:3: line `D=$A$X` has a reference cycle: A -> B -> A\n"+
+		"6:6: line `SELF=$SELF` has an undefined variable: SELF")
+	assert.Empty(t, env)
+
+	// the variables referring to a cycle are reported instead of being expanded to an empty string
+	p = gotenv.NewParser(gotenv.WithForwardReferences(true), gotenv.WithLookup(nil), gotenv.WithStrict(false))
+	env, err = p.Parse(strings.NewReader("A=$B\nB=$A\nD=$A\nE=x$D\nF=${X:=$G}\nG=$X\nH=$X"))
+	assert.EqualError(t, err, "1:3: line `A=$B` has a reference cycle: A -> B -> A\n"+
+		"3:3: line `D=$A` has a reference cycle: A -> B -> A\n"+
+		"4:4: line `E=x$D` has a reference cycle: A -> B -> A\n"+
+		"5:8: line `F=${X:=$G}` has a reference cycle: F -> G -> F\n"+
+		"7:3: line `H=$X` has a reference cycle: F -> G -> F")
+	assert.Empty(t, env)
+}
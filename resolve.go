@@ -0,0 +1,269 @@
+package gotenv
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+)
+
+// Resolution states of an entry
+const (
+	unresolved = iota
+	resolving
+	resolved
+)
+
+// resolver expands the values of entries regardless of the order they are declared in.
+// A reference to a variable resolves to its last declaration, except within a declaration of the variable itself,
+// like PATH=$PATH:/bin, where it resolves to the previous declaration or to the lookup function.
+// A variable which isn't declared resolves to the first entry assigning it, like ${VAR:=word},
+// except within the entries assigning it, where it resolves to the assignments of the entries above them.
+type resolver struct {
+	p         *Parser
+	entries   []*entry
+	last      map[string]int   // index of the last declaration of each variable
+	prev      []int            // index of the previous declaration of the same variable, or -1
+	assigners map[string][]int // indexes of the entries assigning each variable which isn't declared
+
+	state    []int
+	stack    []int // indexes of the entries being resolved
+	vals     []string
+	errs     []error
+	skipped  []bool        // part of a cycle reported by another entry, or referring to an entry in error
+	cycles   []*CycleError // cycle the entry is part of or refers to
+	warnings [][]*ParseError
+	assigned []*OrderedEnv // variables assigned by ${VAR:=word} and ${VAR=word}
+}
+
+// resolve expands the values of entries into env, in declaration order.
+// It returns the problems found with the entries as ParseError values.
+func (p *Parser) resolve(entries []*entry, env *OrderedEnv) []error {
+	n := len(entries)
+	r := &resolver{
+		p:         p,
+		entries:   entries,
+		last:      make(map[string]int),
+		prev:      make([]int, n),
+		assigners: make(map[string][]int),
+		state:     make([]int, n),
+		vals:      make([]string, n),
+		errs:      make([]error, n),
+		skipped:   make([]bool, n),
+		cycles:    make([]*CycleError, n),
+		warnings:  make([][]*ParseError, n),
+		assigned:  make([]*OrderedEnv, n),
+	}
+	for i, e := range entries {
+		r.prev[i] = -1
+		if j, ok := r.last[e.key]; ok {
+			r.prev[i] = j
+		}
+		r.last[e.key] = i
+	}
+	for i, e := range entries {
+		for _, name := range assignments(e.parts) {
+			if _, ok := r.last[name]; ok {
+				continue
+			}
+			if js := r.assigners[name]; len(js) == 0 || js[len(js)-1] != i {
+				r.assigners[name] = append(js, i)
+			}
+		}
+	}
+
+	var errs []error
+	for i, e := range entries {
+		r.resolveEntry(i)
+
+		for _, w := range r.warnings[i] {
+			if p.warn != nil {
+				p.warn(w)
+			}
+		}
+		if err := r.errs[i]; err != nil {
+			errs = append(errs, err)
+			if p.strict {
+				break
+			}
+			continue
+		}
+		if r.skipped[i] {
+			continue
+		}
+
+		env.Set(e.key, r.vals[i])
+		for k, v := range r.assigned[i].All() {
+			if _, ok := env.Get(k); !ok {
+				env.Set(k, v)
+			}
+		}
+	}
+
+	return errs
+}
+
+// resolveEntry expands the value of the entry at the index i, after the entries it refers to.
+// It reports whether the value can be used.
+func (r *resolver) resolveEntry(i int) bool {
+	switch r.state[i] {
+	case resolved:
+		return r.errs[i] == nil && !r.skipped[i]
+	case resolving:
+		r.reportCycle(i)
+		return false
+	}
+
+	r.state[i] = resolving
+	r.stack = append(r.stack, i)
+	defer func() {
+		r.state[i] = resolved
+		r.stack = r.stack[:len(r.stack)-1]
+	}()
+
+	e := r.entries[i]
+	scope := NewOrderedEnv()
+	var cycle *CycleError // cycle of the first variable referred to which can't be resolved because of it
+	var cycleRef string
+	failed := false // a variable referred to can't be resolved because of the error of its declaration
+	if e.parts != nil {
+		for _, name := range references(e.parts) {
+			if _, ok := scope.Get(name); ok {
+				continue
+			}
+			// the lookup function takes precedence over the file
+			if r.p.lookup != nil && !r.p.override {
+				if _, ok := r.p.lookup(name); ok {
+					continue
+				}
+			}
+
+			val, ok, j := r.reference(i, name)
+			switch {
+			case ok:
+				scope.Set(name, val)
+			case j >= 0 && r.cycles[j] != nil:
+				if cycle == nil {
+					cycle, cycleRef = r.cycles[j], name
+				}
+			case j >= 0:
+				failed = true
+			}
+		}
+	}
+	deps := scope.Env()
+
+	// the variables referring to a cycle are reported along with it
+	if cycle != nil && r.errs[i] == nil && !r.skipped[i] {
+		line, col := e.position(refColumn(e.text, &varError{name: cycleRef}) - 1)
+		r.errs[i] = &ParseError{Line: line, Column: col, Text: e.text, Err: cycle}
+		r.cycles[i] = cycle
+	}
+	// the variables referring to an entry in error are skipped silently, the error being reported by the entry
+	if failed && r.errs[i] == nil {
+		r.skipped[i] = true
+	}
+
+	val, warnings, err := r.p.expandEntry(e, scope)
+	r.vals[i] = val
+	// the variables of a cycle, or referring to an entry in error, would be reported as undefined
+	if r.errs[i] != nil || r.skipped[i] {
+		return false
+	}
+	r.warnings[i] = warnings
+	if err != nil {
+		r.errs[i] = err
+		return false
+	}
+
+	r.assigned[i] = NewOrderedEnv()
+	for k, v := range scope.All() {
+		if _, ok := deps[k]; ok {
+			continue
+		}
+		if _, ok := r.last[k]; !ok {
+			r.assigned[i].Set(k, v)
+		}
+	}
+
+	return true
+}
+
+// reference resolves the variable name referred to by the entry at the index i, declared or assigned by other entries.
+// It returns the value of the variable and whether it's defined. When it's not, it returns the index of an entry
+// declaring or assigning the variable which can't be resolved, preferably because of a cycle, or -1.
+func (r *resolver) reference(i int, name string) (string, bool, int) {
+	var js []int
+	switch j, ok := r.last[name]; {
+	case name == r.entries[i].key:
+		if r.prev[i] >= 0 {
+			js = []int{r.prev[i]}
+		}
+	case ok:
+		js = []int{j}
+	default:
+		js = r.assigners[name]
+		if k := slices.Index(js, i); k >= 0 {
+			// within an entry assigning the variable, like the previous declaration of a declared one
+			js = js[:k]
+		}
+	}
+
+	failed := -1
+	for _, j := range js {
+		if !r.resolveEntry(j) {
+			if failed < 0 || r.cycles[failed] == nil {
+				failed = j
+			}
+			continue
+		}
+		if r.entries[j].key == name {
+			return r.vals[j], true, -1
+		}
+		if val, ok := r.assigned[j].Get(name); ok {
+			return val, true, -1
+		}
+	}
+	return "", false, failed
+}
+
+// reportCycle reports the cycle closed by a reference to the entry at the index i, which is being resolved.
+// The error is held by the entry i while the other entries of the cycle are skipped silently.
+func (r *resolver) reportCycle(i int) {
+	start := slices.Index(r.stack, i)
+	path := make([]string, 0, len(r.stack)-start+1)
+	for _, j := range r.stack[start:] {
+		path = append(path, r.entries[j].key)
+	}
+	path = append(path, r.entries[i].key)
+
+	// the entry i may close several cycles, only the first one is reported
+	cycle := cmp.Or(r.cycles[i], &CycleError{Path: path})
+	for _, j := range r.stack[start:] {
+		r.cycles[j] = cmp.Or(r.cycles[j], cycle)
+		if j != i {
+			r.skipped[j] = true
+		}
+	}
+
+	if r.errs[i] != nil {
+		return
+	}
+	e := r.entries[i]
+	line, col := e.position(refColumn(e.text, &varError{name: path[1]}) - 1)
+	r.errs[i] = &ParseError{Line: line, Column: col, Text: e.text, Err: cycle}
+}
+
+// sortByLine sorts the errors by line, keeping the errors which are not ParseError values last.
+func sortByLine(errs []error) {
+	line := func(err error) int {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			return pe.Line
+		}
+		return math.MaxInt
+	}
+	slices.SortStableFunc(errs, func(a, b error) int {
+		return cmp.Compare(line(a), line(b))
+	})
+}
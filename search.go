@@ -0,0 +1,103 @@
+package gotenv
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// search is the configuration of FindUpward.
+type search struct {
+	start   string
+	name    string
+	markers []string
+}
+
+// SearchOption configures FindUpward and LoadUpward.
+type SearchOption func(*search)
+
+// WithSearchStart sets the directory where the search starts, which is the current one by default.
+func WithSearchStart(dir string) SearchOption {
+	return func(s *search) {
+		s.start = dir
+	}
+}
+
+// WithSearchName sets the name of the file to look for, which is `.env` by default.
+func WithSearchName(name string) SearchOption {
+	return func(s *search) {
+		s.name = name
+	}
+}
+
+// WithSearchMarkers stops the search at the directory holding one of the markers, like `go.mod` or `.git`,
+// which is searched as well. By default, the search goes up to the root of the file system.
+func WithSearchMarkers(markers ...string) SearchOption {
+	return func(s *search) {
+		s.markers = markers
+	}
+}
+
+// FindUpward looks for the `.env` file in the current directory, then in its parents up to the root of the file system,
+// and returns the absolute path of the first one found. When there is none, the error holds fs.ErrNotExist.
+func FindUpward(opts ...SearchOption) (string, error) {
+	s := &search{name: ".env"}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	start := s.start
+	if start == "" {
+		start = "."
+	}
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		path := filepath.Join(dir, s.name)
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && !info.IsDir():
+			return path, nil
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			return "", err
+		}
+
+		for _, marker := range s.markers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return "", &fs.PathError{Op: "find", Path: s.name, Err: fs.ErrNotExist}
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", &fs.PathError{Op: "find", Path: s.name, Err: fs.ErrNotExist}
+		}
+		dir = parent
+	}
+}
+
+// LoadUpward loads the first `.env` file found by FindUpward, like Load, and returns its path.
+// It's useful for the tests of nested packages, which run in their own directory:
+//
+//	gotenv.LoadUpward(gotenv.WithSearchMarkers("go.mod"))
+func LoadUpward(opts ...SearchOption) (string, error) {
+	return NewParser().LoadUpward(opts...)
+}
+
+// OverLoadUpward is like LoadUpward but it overrides the existing environment variables.
+func OverLoadUpward(opts ...SearchOption) (string, error) {
+	return NewParser(WithOverride(true)).LoadUpward(opts...)
+}
+
+// LoadUpward is like the LoadUpward function, the file being loaded with the configuration of the Parser.
+func (p *Parser) LoadUpward(opts ...SearchOption) (string, error) {
+	path, err := FindUpward(opts...)
+	if err != nil {
+		return "", err
+	}
+	return path, p.Load(path)
+}
@@ -0,0 +1,92 @@
+package gotenv_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+func TestFindUpward(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".env":                    "A=root",
+		"go.mod":                  "module example.com/root",
+		"proj/go.mod":             "module example.com/proj",
+		"proj/pkg/sub/.env/":      "",
+		"proj/pkg/sub/dir/x.go":   "package dir",
+		"other/.env":              "A=other",
+		"other/app.env":           "A=app",
+		"other/pkg/internal/x.go": "package internal",
+	})
+
+	tests := []struct {
+		start string
+		opts  []gotenv.SearchOption
+		path  string
+	}{
+		{"", nil, ".env"},
+		{"proj/pkg/sub/dir", nil, ".env"},
+		{"proj/pkg/sub/dir", []gotenv.SearchOption{gotenv.WithSearchMarkers("go.mod")}, ""},
+		{"proj/pkg/sub/dir", []gotenv.SearchOption{gotenv.WithSearchMarkers(".git", "go.mod")}, ""},
+		{"proj", []gotenv.SearchOption{gotenv.WithSearchMarkers(".git")}, ".env"},
+		{"other/pkg/internal", []gotenv.SearchOption{gotenv.WithSearchMarkers("go.mod")}, "other/.env"},
+		{"other/pkg/internal", []gotenv.SearchOption{gotenv.WithSearchName("app.env")}, "other/app.env"},
+		{"other", []gotenv.SearchOption{gotenv.WithSearchName("go.mod")}, "go.mod"},
+	}
+
+	for _, tt := range tests {
+		opts := append([]gotenv.SearchOption{gotenv.WithSearchStart(filepath.Join(root, tt.start))}, tt.opts...)
+		path, err := gotenv.FindUpward(opts...)
+		if tt.path == "" {
+			assert.ErrorIs(t, err, fs.ErrNotExist, tt.start)
+			assert.Equal(t, "", path, tt.start)
+			continue
+		}
+		assert.Nil(t, err, tt.start)
+		assert.Equal(t, filepath.Join(root, filepath.FromSlash(tt.path)), path, tt.start)
+	}
+}
+
+func TestFindUpward_workingDirectory(t *testing.T) {
+	path, err := gotenv.FindUpward(gotenv.WithSearchMarkers("go.mod"))
+	assert.Nil(t, err)
+
+	want, err := filepath.Abs(".env")
+	assert.Nil(t, err)
+	assert.Equal(t, want, path)
+}
+
+func TestLoadUpward(t *testing.T) {
+	defer os.Clearenv()
+	root := writeTree(t, map[string]string{
+		".env":          "A=root\nB=root",
+		"proj/go.mod":   "module example.com/proj",
+		"proj/pkg/x.go": "package pkg",
+	})
+
+	os.Setenv("B", "preset")
+	path, err := gotenv.LoadUpward(gotenv.WithSearchStart(filepath.Join(root, "proj", "pkg")))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, ".env"), path)
+	assert.Equal(t, "root", os.Getenv("A"))
+	assert.Equal(t, "preset", os.Getenv("B"))
+
+	path, err = gotenv.OverLoadUpward(gotenv.WithSearchStart(filepath.Join(root, "proj", "pkg")))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, ".env"), path)
+	assert.Equal(t, "root", os.Getenv("B"))
+
+	path, err = gotenv.LoadUpward(gotenv.WithSearchStart(filepath.Join(root, "proj", "pkg")), gotenv.WithSearchMarkers("go.mod"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.EqualError(t, err, "find .env: file does not exist")
+	assert.Equal(t, "", path)
+
+	err = os.WriteFile(filepath.Join(root, "proj", ".env"), []byte("lol$wut"), 0o644)
+	assert.Nil(t, err)
+	path, err = gotenv.LoadUpward(gotenv.WithSearchStart(filepath.Join(root, "proj", "pkg")))
+	assert.ErrorIs(t, err, gotenv.ErrInvalidLine)
+	assert.Equal(t, filepath.Join(root, "proj", ".env"), path)
+}
@@ -0,0 +1,245 @@
+package gotenv
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Characters requiring a value of a systemd environment file to be double quoted, the way systemd writes them
+const systemdNeedQuotes = "\"\\`$*?['()<>|&;! \t\n"
+
+// ParseSystemdEnvFile parses r as a file read by the EnvironmentFile= setting of systemd units
+// and returns the Env key/value pair of valid variables.
+// Lines starting with `#` or `;` are comments. Values may be single quoted, read literally, or double quoted,
+// where a backslash only escapes double quotes, backslashes, backticks, dollar signs and line breaks. Outside of quotes, a backslash escapes any character
+// and continues the value on the next line when it ends the line. Dollar signs are literal and the `export` keyword isn't supported.
+// This function is returning the first invalid line as a ParseError, along with the variables found before it.
+func ParseSystemdEnvFile(r io.Reader) (Env, error) {
+	env := make(Env)
+
+	z, _, err := decode(r)
+	if err != nil {
+		return env, err
+	}
+	data, err := io.ReadAll(z)
+	if err != nil {
+		return env, err
+	}
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case ' ', '\t', '\n':
+			i++
+		case '#', ';':
+			i = skipSystemdComment(s, i)
+		default:
+			key, val, end, err := lexSystemdAssignment(s, i)
+			if err != nil {
+				return env, err
+			}
+			env[key] = val
+			i = end
+		}
+	}
+
+	return env, nil
+}
+
+// ReadSystemdEnvFile is like ParseSystemdEnvFile but it reads the file, the errors of invalid lines holding the filename.
+func ReadSystemdEnvFile(filename string) (Env, error) {
+	return readWith(filename, ParseSystemdEnvFile)
+}
+
+// skipSystemdComment returns the index following the comment starting at the index i of s.
+// Like values, comments are continued by a backslash ending the line.
+func skipSystemdComment(s string, i int) int {
+	for ; i < len(s) && s[i] != '\n'; i++ {
+		if s[i] == '\\' {
+			i++
+		}
+	}
+	return min(i, len(s))
+}
+
+// lexSystemdAssignment lexes the assignment starting at the index start of s.
+// It returns the key and the unquoted value, along with the index following the assignment.
+func lexSystemdAssignment(s string, start int) (string, string, int, error) {
+	fail := func(i int, cause error) error {
+		text, _, _ := strings.Cut(s[start:], "\n")
+		line, col := position(s, i)
+		return &ParseError{Line: line + 1, Column: col, Text: strings.TrimSpace(text), Err: cause}
+	}
+
+	sep := strings.IndexAny(s[start:], "=\n")
+	if sep < 0 || s[start+sep] == '\n' {
+		return "", "", 0, fail(start, ErrInvalidLine)
+	}
+	key := strings.TrimRight(s[start:start+sep], " \t")
+	if !isShellName(key) {
+		return "", "", 0, fail(start, ErrInvalidLine)
+	}
+
+	var sb strings.Builder
+	unquoted := false // within an unquoted part of the value
+	trailing := -1    // start of the blanks ending the value, which are dropped
+	i := start + sep + 1
+loop:
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '\n':
+			break loop
+		case c == '\\':
+			// escaped character, or continued line
+			if i+1 < len(s) && s[i+1] != '\n' {
+				sb.WriteByte(s[i+1])
+			}
+			unquoted, trailing = true, -1
+			i += 2
+			continue
+		case unquoted:
+			if c != ' ' && c != '\t' {
+				trailing = -1
+			} else if trailing < 0 {
+				trailing = sb.Len()
+			}
+			sb.WriteByte(c)
+		case c == ' ' || c == '\t':
+			// blanks around quoted parts
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return "", "", 0, fail(i, ErrMissingQuote)
+			}
+			sb.WriteString(s[i+1 : i+1+end])
+			i += end + 2
+			continue
+		case c == '"':
+			end := lexSystemdQuoted(&sb, s, i+1)
+			if end < 0 {
+				return "", "", 0, fail(i, ErrMissingQuote)
+			}
+			i = end
+			continue
+		default:
+			unquoted = true
+			sb.WriteByte(c)
+		}
+		i++
+	}
+
+	val := sb.String()
+	if trailing >= 0 {
+		val = val[:trailing]
+	}
+	if !isSystemdValue(val) {
+		return "", "", 0, fail(start+sep+1, ErrInvalidLine)
+	}
+	return key, val, min(i, len(s)), nil
+}
+
+// lexSystemdQuoted writes the double quoted value starting at the index i of s, right after the opening quote, to sb.
+// It returns the index following the closing quote, or -1 when there is none.
+func lexSystemdQuoted(sb *strings.Builder, s string, i int) int {
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return i + 1
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case '\n':
+				// continued line
+			case '"', '\\', '`', '$':
+				sb.WriteByte(s[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return -1
+}
+
+// MarshalSystemdEnvFile outputs the given environment as a file read by the EnvironmentFile= setting of systemd units.
+// Variables will be sorted by name, and their values double quoted when they hold blanks or special characters.
+// A variable which systemd can't read, like a name holding a dot or a value holding a control character,
+// is reported as an error holding ErrUnrepresentable.
+func MarshalSystemdEnvFile(env Env) (string, error) {
+	lines := make([]string, 0, len(env))
+	for k, v := range env {
+		if msg := systemdProblem(k, v); msg != "" {
+			return "", &varError{name: k, msg: msg, err: ErrUnrepresentable}
+		}
+		lines = append(lines, k+"="+quoteSystemd(v))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// WriteSystemdEnvFile serializes the given environment like MarshalSystemdEnvFile and writes it to a file
+func WriteSystemdEnvFile(env Env, filename string) error {
+	content, err := MarshalSystemdEnvFile(env)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, content)
+}
+
+// quoteSystemd returns val double quoted when it's needed, escaping the characters which systemd would decode.
+func quoteSystemd(val string) string {
+	if !strings.ContainsAny(val, systemdNeedQuotes) {
+		return val
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(val) + 2)
+	sb.WriteByte('"')
+	for i := 0; i < len(val); i++ {
+		if strings.IndexByte("\"\\`$", val[i]) >= 0 {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(val[i])
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// systemdProblem returns the reason why the variable key can't be written in a systemd environment file, or an empty string.
+func systemdProblem(key, val string) string {
+	switch {
+	case !isShellName(key):
+		return "name not made of letters, digits and underscores"
+	case !isSystemdValue(val):
+		return "value holding a control character or invalid UTF-8"
+	}
+	return ""
+}
+
+// isShellName reports whether s is a valid name of a shell variable, which systemd requires.
+func isShellName(s string) bool {
+	if s == "" || '0' <= s[0] && s[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isWordChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSystemdValue reports whether val is a valid value for systemd, which rejects control characters but tabs and line breaks.
+func isSystemdValue(val string) bool {
+	for i := 0; i < len(val); i++ {
+		if c := val[i]; c < ' ' && c != '\t' && c != '\n' || c == 0x7f {
+			return false
+		}
+	}
+	return utf8.ValidString(val)
+}
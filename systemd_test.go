@@ -0,0 +1,157 @@
+package gotenv_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subosito/gotenv"
+)
+
+var systemdFormats = []struct {
+	in  string
+	out gotenv.Env
+}{
+	{`FOO=bar`, gotenv.Env{"FOO": "bar"}},
+	{"  FOO  =  bar  \t", gotenv.Env{"FOO": "bar"}},
+	{`FOO=bar # not a comment`, gotenv.Env{"FOO": "bar # not a comment"}},
+	{"# comment\n; comment\n\n  # indented\nFOO=bar", gotenv.Env{"FOO": "bar"}},
+	{"# continued \\\nFOO=bar\nBAR=baz", gotenv.Env{"BAR": "baz"}},
+	{`FOO=$BAR ${BAZ}`, gotenv.Env{"FOO": "$BAR ${BAZ}"}},
+	{`FOO='a "b" \n $c'`, gotenv.Env{"FOO": `a "b" \n $c`}},
+	{`FOO="a \"b\" \\ \$c \` + "`" + ` \n \q"`, gotenv.Env{"FOO": "a \"b\" \\ $c ` \\n \\q"}},
+	{"FOO=\"multi\nline\"", gotenv.Env{"FOO": "multi\nline"}},
+	{"FOO=\"con\\\ntinued\"", gotenv.Env{"FOO": "continued"}},
+	{"FOO=con\\\ntinued\nBAR=1", gotenv.Env{"FOO": "continued", "BAR": "1"}},
+	{`FOO=a\ b\"c\\ \ `, gotenv.Env{"FOO": `a b"c\  `}},
+	{`FOO="a" 'b' c`, gotenv.Env{"FOO": "abc"}},
+	{`FOO=a"b"`, gotenv.Env{"FOO": `a"b"`}},
+	{"FOO=", gotenv.Env{"FOO": ""}},
+	{"FOO=\nBAR=1", gotenv.Env{"FOO": "", "BAR": "1"}},
+	{"FOO=1\r\nBAR=2\r\n", gotenv.Env{"FOO": "1", "BAR": "2"}},
+	{"FOO=1\nFOO=2", gotenv.Env{"FOO": "2"}},
+	{"foo_1=bar", gotenv.Env{"foo_1": "bar"}},
+	{"\xEF\xBB\xBFFOO=bar", gotenv.Env{"FOO": "bar"}},
+}
+
+func TestParseSystemdEnvFile(t *testing.T) {
+	for _, tt := range systemdFormats {
+		env, err := gotenv.ParseSystemdEnvFile(strings.NewReader(tt.in))
+		assert.Nil(t, err, tt.in)
+		assert.Equal(t, tt.out, env, tt.in)
+	}
+}
+
+func TestParseSystemdEnvFile_errors(t *testing.T) {
+	errorFormats := []struct {
+		in  string
+		err string
+	}{
+		{"FOO=1\nexport BAR=2", "2:1: line `export BAR=2` doesn't match format"},
+		{"FOO=1\n  BAR", "2:3: line `BAR` doesn't match format"},
+		{"foo.bar=1", "1:1: line `foo.bar=1` doesn't match format"},
+		{"1FOO=1", "1:1: line `1FOO=1` doesn't match format"},
+		{"=1", "1:1: line `=1` doesn't match format"},
+		{"FOO=a\x01b", "1:5: line `FOO=a\x01b` doesn't match format"},
+		{"FOO=1\nBAR='2\nBAZ=3", "2:5: line `BAR='2` has missing quotes"},
+		{"FOO=\"1\\\"", "1:5: line `FOO=\"1\\\"` has missing quotes"},
+	}
+
+	for _, tt := range errorFormats {
+		env, err := gotenv.ParseSystemdEnvFile(strings.NewReader(tt.in))
+		assert.EqualError(t, err, tt.err, tt.in)
+		assert.NotContains(t, env, "BAR", tt.in)
+	}
+}
+
+func TestMarshalSystemdEnvFile(t *testing.T) {
+	env := gotenv.Env{
+		"PLAIN":   "bar",
+		"EMPTY":   "",
+		"SPACED":  " a b ",
+		"DOLLAR":  "$HOME ${USER}",
+		"QUOTES":  `it's "quoted"`,
+		"ESCAPES": "back\\slash `cmd` \\n",
+		"LINES":   "multi\nline\n",
+		"HASH":    "#;",
+		"TAB":     "a\tb",
+	}
+	out, err := gotenv.MarshalSystemdEnvFile(env)
+	assert.Nil(t, err)
+	assert.Equal(t, `DOLLAR="\$HOME \${USER}"
+EMPTY=
+ESCAPES="back\\slash \`+"`cmd\\`"+` \\n"
+HASH="#;"
+LINES="multi
+line
+"
+PLAIN=bar
+QUOTES="it's \"quoted\""
+SPACED=" a b "
+TAB="a	b"`, out)
+
+	back, err := gotenv.ParseSystemdEnvFile(strings.NewReader(out))
+	assert.Nil(t, err)
+	assert.Equal(t, env, back)
+}
+
+func TestMarshalSystemdEnvFile_roundTrip(t *testing.T) {
+	// env files of developers converted for a unit, and back
+	for _, name := range []string{"plain.env", "quoted.env", "backtick.env", "heredoc.env", "exported.env"} {
+		env, err := gotenv.Read(filepath.Join("fixtures", name))
+		assert.Nil(t, err, name)
+
+		out, err := gotenv.MarshalSystemdEnvFile(env)
+		assert.Nil(t, err, name)
+
+		back, err := gotenv.ParseSystemdEnvFile(strings.NewReader(out))
+		assert.Nil(t, err, name)
+		assert.Equal(t, env, back, name)
+	}
+}
+
+func TestMarshalSystemdEnvFile_unrepresentable(t *testing.T) {
+	tests := []struct {
+		env gotenv.Env
+		err string
+	}{
+		{gotenv.Env{"foo.bar": "a"}, "can't be represented: foo.bar: name not made of letters, digits and underscores"},
+		{gotenv.Env{"1A": "a"}, "can't be represented: 1A: name not made of letters, digits and underscores"},
+		{gotenv.Env{"A": "a\rb"}, "can't be represented: A: value holding a control character or invalid UTF-8"},
+		{gotenv.Env{"A": "a\x00b"}, "can't be represented: A: value holding a control character or invalid UTF-8"},
+		{gotenv.Env{"A": "\xff"}, "can't be represented: A: value holding a control character or invalid UTF-8"},
+	}
+
+	for _, tt := range tests {
+		out, err := gotenv.MarshalSystemdEnvFile(tt.env)
+		assert.ErrorIs(t, err, gotenv.ErrUnrepresentable, tt.err)
+		assert.EqualError(t, err, tt.err)
+		assert.Equal(t, "", out)
+	}
+}
+
+func TestWriteSystemdEnvFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sub", "app.env")
+	env := gotenv.Env{"FOO": "a b", "BAZ": "1"}
+
+	err := gotenv.WriteSystemdEnvFile(env, filename)
+	assert.Nil(t, err)
+
+	data, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "BAZ=1\nFOO=\"a b\"\n", string(data))
+
+	back, err := gotenv.ReadSystemdEnvFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, env, back)
+
+	err = gotenv.WriteSystemdEnvFile(gotenv.Env{"a.b": "1"}, filename)
+	assert.ErrorIs(t, err, gotenv.ErrUnrepresentable)
+
+	err = os.WriteFile(filename, []byte("FOO=1\nexport BAR=2"), 0o644)
+	assert.Nil(t, err)
+	_, err = gotenv.ReadSystemdEnvFile(filename)
+	assert.EqualError(t, err, filename+":2:1: line `export BAR=2` doesn't match format")
+}